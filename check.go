@@ -0,0 +1,126 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/becheran/roumon/internal/analysis"
+	"github.com/becheran/roumon/internal/baseline"
+	"github.com/becheran/roumon/internal/client"
+	"github.com/becheran/roumon/internal/model"
+)
+
+// runCheck searches one goroutine dump for likely deadlocks and compares it with a baseline. Returns the exit code.
+// 0 if no problems were found, 1 if there are deadlocks or stacks not covered by the baseline and 2 on errors
+func runCheck(args []string) int {
+	fs := flag.NewFlagSet("roumon check", flag.ExitOnError)
+	baselineFile := fs.String("baseline", "", "Path to the baseline file. Only deadlocks are checked if empty")
+	update := fs.Bool("update", false, "Write a baseline from the dump instead of checking it")
+	host := fs.String("host", "localhost", "The pprof server IP or hostname")
+	port := fs.Int("port", 6060, "The pprof server port")
+	format := fs.String("format", client.FormatStacks, "Goroutine profile format. One of \"stacks\", \"grouped\" or \"proto\". Deadlocks are only detected with \"stacks\"")
+	deadlockAfter := fs.Duration("deadlock-after", time.Minute, "Minimum wait time of blocked goroutines for deadlock detection")
+	stacks := fs.Bool("stacks", false, "Include the full stacks of baseline violations in the output")
+	ignoreRules := addIgnoreFlags(fs)
+	modules := fs.String("module", "", "Comma separated import path prefixes of own code. Detected automatically if empty")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: roumon check [options] [dump.txt]")
+		fmt.Fprintln(fs.Output(), "Scrapes the pprof server once if no dump file is given")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	log.SetOutput(io.Discard)
+	if fs.NArg() > 1 || (*update && *baselineFile == "") {
+		fs.Usage()
+		return 2
+	}
+
+	var groups []model.Group
+	var routines []model.Goroutine
+	if fs.NArg() == 1 {
+		var err error
+		if groups, err = model.LoadDump(fs.Arg(0)); err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			return 2
+		}
+		// Only dumps in the debug=2 format have single goroutines
+		for _, g := range groups {
+			routines = append(routines, g.Routines...)
+		}
+	} else {
+		c := client.NewClient(*host, *port)
+		c.Format = *format
+		scrape := c.ScrapeOnce()
+		if scrape.Err != nil {
+			fmt.Fprintln(os.Stderr, scrape.Err.Error())
+			return 2
+		}
+		groups, routines = scrape.Groups, scrape.Routines
+	}
+	rules, err := ignoreRules()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 2
+	}
+	// Deadlocks are searched in all goroutines. Ignored ones, such as the only running goroutine, still keep the others alive
+	all := routines
+	if routines != nil {
+		routines, _ = rules.FilterRoutines(routines)
+		groups = model.GroupByStack(routines)
+	} else {
+		groups, _ = rules.FilterGroups(groups)
+	}
+	classifier := model.NewClassifier(*modules).Detect(model.Stacks(groups))
+
+	if *update {
+		b := baseline.FromGroups(groups, classifier)
+		if err := b.Save(*baselineFile); err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			return 2
+		}
+		fmt.Printf("Wrote %d stack groups to %s\n", len(b.Entries), *baselineFile)
+		return 0
+	}
+
+	failed := false
+	if all != nil {
+		findings := analysis.Deadlocks(all, *deadlockAfter)
+		for _, f := range findings {
+			fmt.Println(f.String())
+		}
+		if len(findings) > 0 {
+			fmt.Printf("FAIL: %d possible deadlocks\n", len(findings))
+			failed = true
+		} else {
+			fmt.Printf("OK: no deadlocks in %d goroutines\n", len(all))
+		}
+	}
+
+	if *baselineFile != "" {
+		b, err := baseline.Load(*baselineFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			return 2
+		}
+		violations := b.Check(groups)
+		if err := baseline.WriteViolations(os.Stdout, violations, classifier, *stacks); err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			return 2
+		}
+		if len(violations) > 0 {
+			fmt.Printf("FAIL: %d stack groups not covered by the baseline\n", len(violations))
+			failed = true
+		} else {
+			fmt.Printf("OK: %d goroutines match the baseline\n", model.Total(groups))
+		}
+	}
+
+	if failed {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/becheran/roumon/internal/diff"
+	"github.com/becheran/roumon/internal/model"
+	"github.com/becheran/roumon/internal/ui"
+)
+
+// Output formats of the diff subcommand
+const (
+	outputText = "text"
+	outputJSON = "json"
+	outputTUI  = "tui"
+)
+
+// runDiff compares two goroutine dump files and returns the exit code
+func runDiff(args []string) int {
+	fs := flag.NewFlagSet("roumon diff", flag.ExitOnError)
+	output := fs.String("output", outputText, "Output format. One of \"text\", \"json\" or \"tui\"")
+	stacks := fs.Bool("stacks", false, "Include the full stacks in the text output")
+	modules := fs.String("module", "", "Comma separated import path prefixes of own code. Detected automatically if empty")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: roumon diff [options] before.txt after.txt")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return 2
+	}
+
+	before, err := model.LoadDump(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 1
+	}
+	after, err := model.LoadDump(fs.Arg(1))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 1
+	}
+	report := diff.Compare(before, after)
+	classifier := model.NewClassifier(*modules).Detect(model.Stacks(append(before, after...)))
+
+	switch *output {
+	case outputJSON:
+		err = diff.WriteJSON(os.Stdout, report)
+	case outputTUI:
+		err = ui.ShowDiff(report, classifier)
+	case outputText:
+		err = diff.WriteText(os.Stdout, report, classifier, *stacks)
+	default:
+		fmt.Fprintf(os.Stderr, "Invalid output %q. Must be one of %q, %q or %q\n", *output, outputText, outputJSON, outputTUI)
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"flag"
+	"slices"
+
+	"github.com/becheran/roumon/internal/model"
+)
+
+// addIgnoreFlags registers the -ignore and -ignore-file flags.
+// The returned function returns the default ignore rules together with the configured ones after parsing
+func addIgnoreFlags(fs *flag.FlagSet) func() (model.IgnoreRules, error) {
+	rules := slices.Clone(model.DefaultIgnoreRules)
+	fs.Func("ignore", "Hide goroutines matching the rule. One of top=<func>, any=<func> or creator=<func>. A trailing * matches a prefix. Can be repeated", func(text string) error {
+		rule, err := model.ParseIgnoreRule(text)
+		if err != nil {
+			return err
+		}
+		rules = append(rules, rule)
+		return nil
+	})
+	file := fs.String("ignore-file", "", "Path to a file with one ignore rule per line")
+	return func() (model.IgnoreRules, error) {
+		if *file == "" {
+			return rules, nil
+		}
+		fileRules, err := model.LoadIgnoreFile(*file)
+		if err != nil {
+			return nil, err
+		}
+		return append(rules, fileRules...), nil
+	}
+}
@@ -0,0 +1,97 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/becheran/roumon/internal/model"
+)
+
+// Channel is a channel with all goroutines blocked on it
+type Channel struct {
+	// Address of the hchan. Only part of dumps which show runtime frames such as runtime.chansend(0xc000012345, ...),
+	// which the pprof endpoint hides. Zero if unknown. Blocked goroutines are grouped by call site in this case
+	Address     uint64
+	Site        model.StackFrame // Most common call site of the blocked goroutines
+	Senders     []int64          // IDs of goroutines blocked in a send
+	Receivers   []int64          // IDs of goroutines blocked in a receive
+	Selects     []int64          // IDs of goroutines blocked in a select. Only grouped by call site
+	LongestWait time.Duration    // Longest wait time of all blocked goroutines. Only reported from one minute on
+	SendWait    time.Duration    // Longest wait time of the blocked senders
+}
+
+// Name returns the address of the channel or the call site if the address is unknown
+func (c Channel) Name() string {
+	if c.Address == 0 {
+		return "call site " + c.Site.Location()
+	}
+	return fmt.Sprintf("chan %#x", c.Address)
+}
+
+// Waiting returns the number of blocked goroutines
+func (c Channel) Waiting() int {
+	return len(c.Senders) + len(c.Receivers) + len(c.Selects)
+}
+
+// StuckSenders returns true if goroutines block on sending for at least the threshold. Senders which are never
+// received from are the most common goroutine leak, while receivers such as idle workers usually wait for long.
+// The runtime never blocks senders and receivers of the same channel at once, so the wait time is the only hint
+func (c Channel) StuckSenders(threshold time.Duration) bool {
+	return len(c.Senders) > 0 && c.SendWait >= threshold
+}
+
+// ChannelWaits groups the goroutines blocked on channels by the address of the channel or by call site
+// if the address is not part of the dump. Channels with the most blocked goroutines first
+func ChannelWaits(routines []model.Goroutine) []Channel {
+	var channels []Channel
+	index := make(map[string]int)
+	siteCounts := make([]map[string]int, 0)
+	for _, g := range routines {
+		if g.Status.Category() != model.CategoryChannel {
+			continue
+		}
+		target, site, ok := waitTarget(g)
+		if !ok || target.Kind != TargetChannel {
+			// Dumps of the pprof endpoint hide the runtime frames. Group by the first frame outside of the runtime
+			target = Target{Kind: TargetChannel}
+			if site < len(g.StackTrace) {
+				target.Site = g.StackTrace[site]
+			}
+		}
+		key := fmt.Sprintf("%#x", target.Address)
+		if target.Address == 0 {
+			key += " " + target.Site.Location()
+		}
+		i, ok := index[key]
+		if !ok {
+			i = len(channels)
+			index[key] = i
+			channels = append(channels, Channel{Address: target.Address, Site: target.Site})
+			siteCounts = append(siteCounts, make(map[string]int))
+		}
+		c := &channels[i]
+		switch g.Status {
+		case model.StatusChanSend, model.StatusChanSendNil:
+			c.Senders = append(c.Senders, g.ID)
+			c.SendWait = max(c.SendWait, g.WaitSince)
+		case model.StatusChanReceive, model.StatusChanReceiveNil:
+			c.Receivers = append(c.Receivers, g.ID)
+		default:
+			c.Selects = append(c.Selects, g.ID)
+		}
+		c.LongestWait = max(c.LongestWait, g.WaitSince)
+		loc := target.Site.Location()
+		siteCounts[i][loc]++
+		if siteCounts[i][loc] > siteCounts[i][c.Site.Location()] {
+			c.Site = target.Site
+		}
+	}
+	sort.SliceStable(channels, func(i, j int) bool {
+		if channels[i].Waiting() != channels[j].Waiting() {
+			return channels[i].Waiting() > channels[j].Waiting()
+		}
+		return channels[i].LongestWait > channels[j].LongestWait
+	})
+	return channels
+}
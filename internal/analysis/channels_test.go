@@ -0,0 +1,143 @@
+package analysis_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/becheran/roumon/internal/analysis"
+	"github.com/stretchr/testify/assert"
+)
+
+// Dump of the pprof endpoint which hides the runtime frames
+var trace_channelWaits = `goroutine 1 [running]:
+runtime/pprof.writeGoroutineStacks({0x9f1f70, 0x24bcaf234058})
+	/usr/local/go/src/runtime/pprof/pprof.go:816 +0x69
+runtime/pprof.writeGoroutine({0x9f1f70?, 0x24bcaf234058?}, 0x4095b5?)
+	/usr/local/go/src/runtime/pprof/pprof.go:779 +0x25
+runtime/pprof.(*Profile).WriteTo(0x6e979a?, {0x9f1f70?, 0x24bcaf234058?}, 0x24bcaf29c068?)
+	/usr/local/go/src/runtime/pprof/pprof.go:405 +0x149
+main.main()
+	/app/main.go:37 +0x1c5
+
+goroutine 7 [chan send]:
+main.produce(0x0?, 0x0)
+	/app/main.go:13 +0x1d
+created by main.main in goroutine 1
+	/app/main.go:25 +0x54
+
+goroutine 8 [chan send, 5 minutes]:
+main.produce(0x0?, 0x1)
+	/app/main.go:13 +0x1d
+created by main.main in goroutine 1
+	/app/main.go:25 +0x54
+
+goroutine 9 [chan send]:
+main.produce(0x0?, 0x2)
+	/app/main.go:13 +0x1d
+created by main.main in goroutine 1
+	/app/main.go:25 +0x54
+
+goroutine 10 [chan receive]:
+main.consume(0x0?)
+	/app/main.go:18 +0x25
+created by main.main in goroutine 1
+	/app/main.go:27 +0xf6
+
+goroutine 11 [chan receive]:
+main.consume(0x0?)
+	/app/main.go:18 +0x25
+created by main.main in goroutine 1
+	/app/main.go:28 +0x13c
+
+goroutine 12 [select]:
+main.main.func1()
+	/app/main.go:30 +0x65
+created by main.main in goroutine 1
+	/app/main.go:29 +0x185`
+
+// Crash dump with GOTRACEBACK=system which shows the runtime frames with the channel addresses
+var trace_channelWaitsSystem = `goroutine 7 gp=0x3efad7c76000 m=nil [chan send]:
+runtime.gopark(0x0?, 0x0?, 0x0?, 0x0?, 0x0?)
+	/usr/local/go/src/runtime/proc.go:474 +0xca fp=0x3efad7c08f00 sp=0x3efad7c08ee0 pc=0x48c12a
+runtime.chansend(0x3efad7c380e0, 0x3efad7c08fc8, 0x1, 0x0?)
+	/usr/local/go/src/runtime/chan.go:283 +0x3fc fp=0x3efad7c08f70 sp=0x3efad7c08f00 pc=0x418c9c
+runtime.chansend1(0x0?, 0x0?)
+	/usr/local/go/src/runtime/chan.go:161 +0x17 fp=0x3efad7c08fa0 sp=0x3efad7c08f70 pc=0x418897
+main.produce(0x0?, 0x0)
+	/app/main.go:13 +0x1d fp=0x3efad7c08fc0 sp=0x3efad7c08fa0 pc=0x67d6bd
+main.main.gowrap1()
+	/app/main.go:25 +0x1b fp=0x3efad7c08fe0 sp=0x3efad7c08fc0 pc=0x67da7b
+runtime.goexit({})
+	/usr/local/go/src/runtime/asm_amd64.s:1264 +0x1 fp=0x3efad7c08fe8 sp=0x3efad7c08fe0 pc=0x492fc1
+created by main.main in goroutine 1
+	/app/main.go:25 +0x54
+
+goroutine 10 gp=0x3efad7c765a0 m=nil [chan receive]:
+runtime.gopark(0x0?, 0x0?, 0x0?, 0x0?, 0x0?)
+	/usr/local/go/src/runtime/proc.go:474 +0xca fp=0x3efad7c02700 sp=0x3efad7c026e0 pc=0x48c12a
+runtime.chanrecv(0x3efad7c38150, 0x3efad7c027b0, 0x1)
+	/usr/local/go/src/runtime/chan.go:667 +0x4ae fp=0x3efad7c02778 sp=0x3efad7c02700 pc=0x419bae
+runtime.chanrecv1(0x0?, 0x0?)
+	/usr/local/go/src/runtime/chan.go:509 +0x12 fp=0x3efad7c027a0 sp=0x3efad7c02778 pc=0x4196f2
+main.consume(0x0?)
+	/app/main.go:18 +0x25 fp=0x3efad7c027c8 sp=0x3efad7c027a0 pc=0x67d705
+main.main.gowrap2()
+	/app/main.go:27 +0x17 fp=0x3efad7c027e0 sp=0x3efad7c027c8 pc=0x67da37
+runtime.goexit({})
+	/usr/local/go/src/runtime/asm_amd64.s:1264 +0x1 fp=0x3efad7c027e8 sp=0x3efad7c027e0 pc=0x492fc1
+created by main.main in goroutine 1
+	/app/main.go:27 +0xf6
+
+goroutine 12 gp=0x3efad7c76960 m=nil [select]:
+runtime.gopark(0x3efad7c037b0?, 0x2?, 0xa?, 0x2e?, 0x3efad7c037a4?)
+	/usr/local/go/src/runtime/proc.go:474 +0xca fp=0x3efad7c03630 sp=0x3efad7c03610 pc=0x48c12a
+runtime.selectgo(0x3efad7c037b0, 0x3efad7c037a0, 0x0?, 0x0, 0x0?, 0x1)
+	/usr/local/go/src/runtime/select.go:351 +0xa97 fp=0x3efad7c03770 sp=0x3efad7c03630 pc=0x465537
+main.main.func1()
+	/app/main.go:30 +0x65 fp=0x3efad7c037e0 sp=0x3efad7c03770 pc=0x67d9c5
+runtime.goexit({})
+	/usr/local/go/src/runtime/asm_amd64.s:1264 +0x1 fp=0x3efad7c037e8 sp=0x3efad7c037e0 pc=0x492fc1
+created by main.main in goroutine 1
+	/app/main.go:29 +0x185`
+
+func TestChannelWaits(t *testing.T) {
+	channels := analysis.ChannelWaits(parse(t, trace_channelWaits))
+	assert.Len(t, channels, 3)
+
+	assert.Equal(t, "call site /app/main.go:13", channels[0].Name())
+	assert.Equal(t, []int64{7, 8, 9}, channels[0].Senders)
+	assert.Empty(t, channels[0].Receivers)
+	assert.Equal(t, "main.produce", channels[0].Site.FuncName)
+	assert.Equal(t, 5*time.Minute, channels[0].SendWait)
+	assert.True(t, channels[0].StuckSenders(time.Minute))
+	assert.False(t, channels[0].StuckSenders(10*time.Minute))
+
+	// Idle receivers are not stuck
+	assert.Equal(t, "call site /app/main.go:18", channels[1].Name())
+	assert.Equal(t, []int64{10, 11}, channels[1].Receivers)
+	assert.False(t, channels[1].StuckSenders(0))
+
+	assert.Equal(t, "call site /app/main.go:30", channels[2].Name())
+	assert.Equal(t, []int64{12}, channels[2].Selects)
+	assert.False(t, channels[2].StuckSenders(0))
+}
+
+func TestChannelWaitsSystem(t *testing.T) {
+	channels := analysis.ChannelWaits(parse(t, trace_channelWaitsSystem))
+	assert.Len(t, channels, 3)
+
+	assert.Equal(t, "chan 0x3efad7c380e0", channels[0].Name())
+	assert.Equal(t, []int64{7}, channels[0].Senders)
+	assert.Equal(t, "main.produce", channels[0].Site.FuncName)
+	// Blocked for less than a minute
+	assert.True(t, channels[0].StuckSenders(0))
+	assert.False(t, channels[0].StuckSenders(time.Minute))
+
+	assert.Equal(t, "chan 0x3efad7c38150", channels[1].Name())
+	assert.Equal(t, []int64{10}, channels[1].Receivers)
+	assert.Equal(t, "main.consume", channels[1].Site.FuncName)
+
+	// Selects wait on several channels and are grouped by call site
+	assert.Equal(t, "call site /app/main.go:30", channels[2].Name())
+	assert.Equal(t, []int64{12}, channels[2].Selects)
+}
@@ -0,0 +1,217 @@
+package analysis
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/becheran/roumon/internal/model"
+)
+
+// FindingKind is the kind of a likely deadlock
+type FindingKind string
+
+// Kinds of findings
+const (
+	StuckSystem    FindingKind = "stuck"           // All goroutines are blocked on channels or locks
+	WaitCycle      FindingKind = "cycle"           // Goroutines wait on objects referenced by each other
+	BlockedForever FindingKind = "blocked forever" // Goroutines block on a nil channel or an empty select
+)
+
+// Finding is a likely deadlock
+type Finding struct {
+	Kind     FindingKind
+	Summary  string
+	Routines []int64 // IDs of the involved goroutines
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: %s (goroutines %s)", strings.ToUpper(string(f.Kind)), f.Summary, JoinIDs(f.Routines, 10))
+}
+
+// JoinIDs joins the goroutine IDs with at most limit entries followed by ... if there are more
+func JoinIDs(routines []int64, limit int) string {
+	ids := make([]string, 0, min(len(routines), limit+1))
+	for i, id := range routines {
+		if i == limit {
+			ids = append(ids, "...")
+			break
+		}
+		ids = append(ids, fmt.Sprintf("%d", id))
+	}
+	return strings.Join(ids, ", ")
+}
+
+// blocked returns true if the goroutine waits on a channel or a lock for at least the threshold
+func blocked(g model.Goroutine, threshold time.Duration) bool {
+	c := g.Status.Category()
+	return (c == model.CategoryChannel || c == model.CategoryLock) && g.WaitSince >= threshold
+}
+
+// Deadlocks returns likely deadlocks of the goroutines. Goroutines must be blocked for at least the threshold.
+// The dump only reports wait times of at least one minute
+func Deadlocks(routines []model.Goroutine, threshold time.Duration) []Finding {
+	var findings []Finding
+	if f, ok := stuckSystem(routines, threshold); ok {
+		findings = append(findings, f)
+	}
+	findings = append(findings, waitCycles(routines, threshold)...)
+	findings = append(findings, blockedForever(routines)...)
+	return findings
+}
+
+// Packages of the HTTP server goroutines which serve the dump itself
+var serverPackages = map[string]bool{"net/http": true, "net": true, "internal/poll": true, "bufio": true, "runtime": true}
+
+// external returns true for goroutines which cannot take part in a deadlock of the application.
+// Runtime system goroutines, goroutines waiting on the network or in system calls which an external event can wake up
+// such as the accept loop of the pprof HTTP server, the goroutine which writes the dump and HTTP server goroutines
+// without application frames such as the background read of the scrape connection
+func external(g model.Goroutine) bool {
+	switch g.Status.Category() {
+	case model.CategorySystem, model.CategoryIO:
+		return true
+	}
+	server := g.CratedBy != nil && g.CratedBy.Func.ImportPath == "net/http"
+	for _, f := range g.StackTrace {
+		if f.Func.ImportPath == "runtime/pprof" {
+			return true
+		}
+		server = server && serverPackages[f.Func.ImportPath]
+	}
+	return server
+}
+
+// stuckSystem reports if every goroutine which is not external is blocked
+func stuckSystem(routines []model.Goroutine, threshold time.Duration) (Finding, bool) {
+	f := Finding{Kind: StuckSystem}
+	for _, g := range routines {
+		if external(g) {
+			continue
+		}
+		if !blocked(g, threshold) {
+			return Finding{}, false
+		}
+		f.Routines = append(f.Routines, g.ID)
+	}
+	if len(f.Routines) == 0 {
+		return Finding{}, false
+	}
+	f.Summary = fmt.Sprintf("all %d goroutines are blocked on channels or locks for at least %s", len(f.Routines), threshold)
+	return f, true
+}
+
+// waitCycles reports cycles of blocked goroutines where each one waits on an object which is referenced
+// by the arguments of the next one. The next goroutine possibly holds the lock or is the only one which could use the channel.
+// Channel addresses are only known from runtime frames, which the pprof endpoint hides, so live dumps only show lock cycles
+func waitCycles(routines []model.Goroutine, threshold time.Duration) []Finding {
+	type node struct {
+		g      model.Goroutine
+		target Target
+		refs   map[uint64]bool
+	}
+	var nodes []node
+	for _, g := range routines {
+		if !blocked(g, threshold) {
+			continue
+		}
+		target, site, ok := waitTarget(g)
+		if !ok || target.Address == 0 {
+			continue
+		}
+		nodes = append(nodes, node{g: g, target: target, refs: references(g, site)})
+	}
+
+	// Edge from a to b if a waits for an object which b references but does not wait for itself
+	edges := make([][]int, len(nodes))
+	for a := range nodes {
+		for b := range nodes {
+			if a != b && nodes[a].target.Address != nodes[b].target.Address && nodes[b].refs[nodes[a].target.Address] {
+				edges[a] = append(edges[a], b)
+			}
+		}
+	}
+
+	var findings []Finding
+	for _, cycle := range stronglyConnected(edges) {
+		if len(cycle) < 2 {
+			continue
+		}
+		slices.Sort(cycle)
+		f := Finding{Kind: WaitCycle}
+		parts := make([]string, len(cycle))
+		for i, n := range cycle {
+			f.Routines = append(f.Routines, nodes[n].g.ID)
+			parts[i] = fmt.Sprintf("%d waits for %s %#x", nodes[n].g.ID, nodes[n].target.Kind, nodes[n].target.Address)
+		}
+		f.Summary = "goroutines wait for each other: " + strings.Join(parts, ", ")
+		findings = append(findings, f)
+	}
+	return findings
+}
+
+// stronglyConnected returns the strongly connected components of the graph with Tarjan's algorithm
+func stronglyConnected(edges [][]int) [][]int {
+	index := make([]int, len(edges))
+	low := make([]int, len(edges))
+	onStack := make([]bool, len(edges))
+	for i := range index {
+		index[i] = -1
+	}
+	var stack []int
+	var components [][]int
+	next := 0
+	var visit func(v int)
+	visit = func(v int) {
+		index[v], low[v] = next, next
+		next++
+		stack = append(stack, v)
+		onStack[v] = true
+		for _, w := range edges[v] {
+			if index[w] < 0 {
+				visit(w)
+				low[v] = min(low[v], low[w])
+			} else if onStack[w] {
+				low[v] = min(low[v], index[w])
+			}
+		}
+		if low[v] == index[v] {
+			var component []int
+			for {
+				w := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[w] = false
+				component = append(component, w)
+				if w == v {
+					break
+				}
+			}
+			components = append(components, component)
+		}
+	}
+	for v := range edges {
+		if index[v] < 0 {
+			visit(v)
+		}
+	}
+	return components
+}
+
+// blockedForever reports goroutines which can never be woken up
+func blockedForever(routines []model.Goroutine) []Finding {
+	var findings []Finding
+	for _, status := range []model.Status{model.StatusChanReceiveNil, model.StatusChanSendNil, model.StatusSelectNoCases} {
+		f := Finding{Kind: BlockedForever}
+		for _, g := range routines {
+			if g.Status == status {
+				f.Routines = append(f.Routines, g.ID)
+			}
+		}
+		if len(f.Routines) > 0 {
+			f.Summary = fmt.Sprintf("%d goroutines in %s", len(f.Routines), status)
+			findings = append(findings, f)
+		}
+	}
+	return findings
+}
@@ -0,0 +1,225 @@
+package analysis_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/becheran/roumon/internal/analysis"
+	"github.com/becheran/roumon/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+var trace_deadlock = `goroutine 6 [sync.Mutex.Lock, 2 minutes]:
+internal/sync.runtime_SemacquireMutex(0x0?, 0x0?, 0x0?)
+	/usr/local/go/src/runtime/sema.go:95 +0x25
+internal/sync.(*Mutex).lockSlow(0xc000010130)
+	/usr/local/go/src/internal/sync/mutex.go:149 +0x15a
+internal/sync.(*Mutex).Lock(...)
+	/usr/local/go/src/internal/sync/mutex.go:70
+sync.(*Mutex).Lock(...)
+	/usr/local/go/src/sync/mutex.go:46
+main.(*Account).transfer(0xc000010120, 0xc000010130)
+	/app/main.go:18 +0x5b
+created by main.main in goroutine 1
+	/app/main.go:23 +0xa7
+
+goroutine 7 [sync.Mutex.Lock, 2 minutes]:
+internal/sync.runtime_SemacquireMutex(0x0?, 0x0?, 0x0?)
+	/usr/local/go/src/runtime/sema.go:95 +0x25
+internal/sync.(*Mutex).lockSlow(0xc000010120)
+	/usr/local/go/src/internal/sync/mutex.go:149 +0x15a
+internal/sync.(*Mutex).Lock(...)
+	/usr/local/go/src/internal/sync/mutex.go:70
+sync.(*Mutex).Lock(...)
+	/usr/local/go/src/sync/mutex.go:46
+main.(*Account).transfer(0xc000010130, 0xc000010120)
+	/app/main.go:18 +0x5b
+created by main.main in goroutine 1
+	/app/main.go:24 +0x105
+
+goroutine 11 [sync.WaitGroup.Wait, 3 minutes]:
+sync.runtime_SemacquireWaitGroup(0x0?, 0x0?)
+	/usr/local/go/src/runtime/sema.go:114 +0x2e
+sync.(*WaitGroup).Wait(0xc000010140)
+	/usr/local/go/src/sync/waitgroup.go:206 +0x85
+main.main()
+	/app/main.go:33 +0x17
+
+goroutine 4 [GC worker (idle)]:
+runtime.gopark(0x0?, 0x0?, 0x0?, 0x0?, 0x0?)
+	/usr/local/go/src/runtime/proc.go:435 +0xce
+created by runtime.gcBgMarkStartWorkers in goroutine 1
+	/usr/local/go/src/runtime/mgc.go:1373 +0x105`
+
+func parse(t *testing.T, trace string) []model.Goroutine {
+	routines, err := model.ParseStackFrameStrict(strings.NewReader(trace))
+	assert.Nil(t, err)
+	return routines
+}
+
+func TestWaitTarget(t *testing.T) {
+	routines := parse(t, trace_deadlock)
+	target, ok := analysis.WaitTarget(routines[0])
+	assert.True(t, ok)
+	assert.Equal(t, analysis.TargetMutex, target.Kind)
+	assert.Equal(t, uint64(0xc000010130), target.Address)
+	assert.Equal(t, "main.(*Account).transfer", target.Site.FuncName)
+
+	target, ok = analysis.WaitTarget(routines[2])
+	assert.True(t, ok)
+	assert.Equal(t, analysis.TargetWaitGroup, target.Kind)
+	assert.Equal(t, uint64(0xc000010140), target.Address)
+	assert.Equal(t, "main.main", target.Site.FuncName)
+
+	_, ok = analysis.WaitTarget(routines[3])
+	assert.False(t, ok)
+
+	rlock := parse(t, `goroutine 10 [sync.RWMutex.RLock]:
+sync.runtime_SemacquireRWMutexR(0x0?, 0x0?, 0x0?)
+	/usr/local/go/src/runtime/sema.go:100 +0x25
+sync.(*RWMutex).RLock(...)
+	/usr/local/go/src/sync/rwmutex.go:74
+main.main.func3()
+	/app/main.go:30 +0x31`)
+	target, ok = analysis.WaitTarget(rlock[0])
+	assert.True(t, ok)
+	assert.Equal(t, analysis.TargetRWMutex, target.Kind)
+	assert.Equal(t, uint64(0), target.Address)
+}
+
+func TestParsePointer(t *testing.T) {
+	v, ok := analysis.ParsePointer("0xc000010130")
+	assert.True(t, ok)
+	assert.Equal(t, uint64(0xc000010130), v)
+	for _, invalid := range []string{"...", "0x1?", "0x0", "{0x1, 0x2}", "12"} {
+		_, ok := analysis.ParsePointer(invalid)
+		assert.False(t, ok, invalid)
+	}
+}
+
+func TestDeadlocks(t *testing.T) {
+	routines := parse(t, trace_deadlock)
+	findings := analysis.Deadlocks(routines, time.Minute)
+	assert.Len(t, findings, 2)
+	assert.Equal(t, analysis.StuckSystem, findings[0].Kind)
+	assert.Equal(t, []int64{6, 7, 11}, findings[0].Routines)
+	assert.Equal(t, analysis.WaitCycle, findings[1].Kind)
+	assert.Equal(t, []int64{6, 7}, findings[1].Routines)
+	assert.Equal(t, "CYCLE: goroutines wait for each other: 6 waits for mutex 0xc000010130, 7 waits for mutex 0xc000010120 (goroutines 6, 7)", findings[1].String())
+
+	// Wait times below the threshold
+	assert.Empty(t, analysis.Deadlocks(routines, 5*time.Minute))
+
+	// A running goroutine can still release the locks
+	running := append(routines, model.Goroutine{ID: 1, Status: model.StatusRunning})
+	findings = analysis.Deadlocks(running, time.Minute)
+	assert.Len(t, findings, 1)
+	assert.Equal(t, analysis.WaitCycle, findings[0].Kind)
+}
+
+// Dump of the pprof endpoint with the goroutines of its HTTP server
+var trace_liveDeadlock = `goroutine 33 [running]:
+runtime/pprof.writeGoroutineStacks({0x9f2588, 0x16ec7da7e000})
+	/usr/local/go/src/runtime/pprof/pprof.go:816 +0x69
+runtime/pprof.writeGoroutine({0x9f2588?, 0x16ec7da7e000?}, 0x16ec7da38420?)
+	/usr/local/go/src/runtime/pprof/pprof.go:779 +0x25
+runtime/pprof.(*Profile).WriteTo(0xa376a0?, {0x9f2588?, 0x16ec7da7e000?}, 0xc?)
+	/usr/local/go/src/runtime/pprof/pprof.go:405 +0x149
+net/http/pprof.handler.ServeHTTP({0x16ec7d9784f1, 0x9}, {0x9f4568, 0x16ec7da7e000}, 0x16ec7da0e140)
+	/usr/local/go/src/net/http/pprof/pprof.go:272 +0x554
+net/http.(*conn).serve(0x16ec7da061b0, {0x9f4938, 0x16ec7da6e000})
+	/usr/local/go/src/net/http/server.go:2137 +0x6dc
+created by net/http.(*Server).Serve in goroutine 1
+	/usr/local/go/src/net/http/server.go:3581 +0x4fd
+
+goroutine 42 [runnable]:
+net/http.(*connReader).startBackgroundRead.gowrap2()
+	/usr/local/go/src/net/http/server.go:742
+runtime.goexit({})
+	/usr/local/go/src/runtime/asm_amd64.s:1264 +0x1
+created by net/http.(*connReader).startBackgroundRead in goroutine 33
+	/usr/local/go/src/net/http/server.go:742 +0xba
+
+goroutine 1 [IO wait]:
+internal/poll.runtime_pollWait(0x7f776f045e00, 0x72)
+	/usr/local/go/src/runtime/netpoll.go:351 +0x85
+internal/poll.(*pollDesc).wait(0x16ec7d9fe180?, 0x100?, 0x0)
+	/usr/local/go/src/internal/poll/fd_poll_runtime.go:84 +0x27
+internal/poll.(*pollDesc).waitRead(...)
+	/usr/local/go/src/internal/poll/fd_poll_runtime.go:89
+internal/poll.(*FD).Accept(0x16ec7d9fe180)
+	/usr/local/go/src/internal/poll/fd_unix.go:618 +0x27d
+net.(*netFD).accept(0x16ec7d9fe180)
+	/usr/local/go/src/net/fd_unix.go:149 +0x29
+net.(*TCPListener).accept(0x16ec7d9be340)
+	/usr/local/go/src/net/tcpsock_posix.go:159 +0x1b
+net.(*TCPListener).Accept(0x16ec7d9be340)
+	/usr/local/go/src/net/tcpsock.go:387 +0x30
+net/http.(*Server).Serve(0x16ec7da0e000, {0x9f4658, 0x16ec7d9be340})
+	/usr/local/go/src/net/http/server.go:3551 +0x379
+net/http.(*Server).ListenAndServe(0x16ec7da0e000)
+	/usr/local/go/src/net/http/server.go:3462 +0x71
+net/http.ListenAndServe(...)
+	/usr/local/go/src/net/http/server.go:3813
+main.main()
+	/tmp/dl/main.go:43 +0x345
+
+goroutine 7 [sync.Mutex.Lock, 24 minutes]:
+internal/sync.runtime_SemacquireMutex(0x0?, 0x0?, 0x0?)
+	/usr/local/go/src/runtime/sema.go:95 +0x25
+internal/sync.(*Mutex).lockSlow(0x16ec7d96e270)
+	/usr/local/go/src/internal/sync/mutex.go:149 +0x15a
+internal/sync.(*Mutex).Lock(...)
+	/usr/local/go/src/internal/sync/mutex.go:70
+sync.(*Mutex).Lock(...)
+	/usr/local/go/src/sync/mutex.go:46
+main.(*S).lockBoth(0x16ec7d96e260, 0x16ec7d96e270)
+	/tmp/dl/main.go:19 +0x65
+created by main.main in goroutine 1
+	/tmp/dl/main.go:26 +0xa7
+
+goroutine 8 [sync.Mutex.Lock, 24 minutes]:
+internal/sync.runtime_SemacquireMutex(0x0?, 0x0?, 0x0?)
+	/usr/local/go/src/runtime/sema.go:95 +0x25
+internal/sync.(*Mutex).lockSlow(0x16ec7d96e260)
+	/usr/local/go/src/internal/sync/mutex.go:149 +0x15a
+internal/sync.(*Mutex).Lock(...)
+	/usr/local/go/src/internal/sync/mutex.go:70
+sync.(*Mutex).Lock(...)
+	/usr/local/go/src/sync/mutex.go:46
+main.(*S).lockBoth(0x16ec7d96e270, 0x16ec7d96e260)
+	/tmp/dl/main.go:19 +0x65
+created by main.main in goroutine 1
+	/tmp/dl/main.go:27 +0x105`
+
+func TestDeadlocksLive(t *testing.T) {
+	routines := parse(t, trace_liveDeadlock)
+	findings := analysis.Deadlocks(routines, time.Minute)
+	assert.Len(t, findings, 2)
+	assert.Equal(t, analysis.StuckSystem, findings[0].Kind)
+	assert.Equal(t, []int64{7, 8}, findings[0].Routines)
+	assert.Equal(t, analysis.WaitCycle, findings[1].Kind)
+	assert.Equal(t, []int64{7, 8}, findings[1].Routines)
+}
+
+func TestBlockedForever(t *testing.T) {
+	routines := []model.Goroutine{
+		{ID: 1, Status: model.StatusRunning},
+		{ID: 2, Status: model.StatusChanReceiveNil},
+		{ID: 3, Status: model.StatusSelectNoCases},
+		{ID: 4, Status: model.StatusChanReceiveNil},
+	}
+	findings := analysis.Deadlocks(routines, time.Minute)
+	assert.Len(t, findings, 2)
+	assert.Equal(t, analysis.BlockedForever, findings[0].Kind)
+	assert.Equal(t, []int64{2, 4}, findings[0].Routines)
+	assert.Equal(t, "2 goroutines in chan receive (nil chan)", findings[0].Summary)
+	assert.Equal(t, []int64{3}, findings[1].Routines)
+}
+
+func TestJoinIDs(t *testing.T) {
+	assert.Equal(t, "", analysis.JoinIDs(nil, 2))
+	assert.Equal(t, "1, 2", analysis.JoinIDs([]int64{1, 2}, 2))
+	assert.Equal(t, "1, 2, ...", analysis.JoinIDs([]int64{1, 2, 3}, 2))
+}
@@ -0,0 +1,68 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/becheran/roumon/internal/model"
+)
+
+// Lock is a mutex with all goroutines waiting for it
+type Lock struct {
+	Kind        TargetKind       // TargetMutex or TargetRWMutex
+	Address     uint64           // Zero if the address is not part of the dump. Waiters are grouped by call site in this case
+	Waiters     []int64          // IDs of the waiting goroutines
+	LongestWait time.Duration    // Longest wait time of all waiters. Only reported from one minute on
+	Site        model.StackFrame // Most common call site of the waiters
+	Sites       int              // Number of different call sites
+}
+
+// Name returns the kind and address of the lock such as mutex 0xc000012345
+func (l Lock) Name() string {
+	if l.Address == 0 {
+		return fmt.Sprintf("%s (unknown address)", l.Kind)
+	}
+	return fmt.Sprintf("%s %#x", l.Kind, l.Address)
+}
+
+// HotLocks groups the goroutines waiting for a mutex or read write mutex by the address of the lock.
+// Locks with the most waiters first
+func HotLocks(routines []model.Goroutine) []Lock {
+	var locks []Lock
+	index := make(map[string]int)
+	siteCounts := make([]map[string]int, 0)
+	for _, g := range routines {
+		target, ok := WaitTarget(g)
+		if !ok || (target.Kind != TargetMutex && target.Kind != TargetRWMutex) {
+			continue
+		}
+		site := target.Site.Location()
+		key := fmt.Sprintf("%s %#x", target.Kind, target.Address)
+		if target.Address == 0 {
+			key += " " + site
+		}
+		i, ok := index[key]
+		if !ok {
+			i = len(locks)
+			index[key] = i
+			locks = append(locks, Lock{Kind: target.Kind, Address: target.Address, Site: target.Site})
+			siteCounts = append(siteCounts, make(map[string]int))
+		}
+		l := &locks[i]
+		l.Waiters = append(l.Waiters, g.ID)
+		l.LongestWait = max(l.LongestWait, g.WaitSince)
+		siteCounts[i][site]++
+		if siteCounts[i][site] > siteCounts[i][l.Site.Location()] {
+			l.Site = target.Site
+		}
+		l.Sites = len(siteCounts[i])
+	}
+	sort.SliceStable(locks, func(i, j int) bool {
+		if len(locks[i].Waiters) != len(locks[j].Waiters) {
+			return len(locks[i].Waiters) > len(locks[j].Waiters)
+		}
+		return locks[i].LongestWait > locks[j].LongestWait
+	})
+	return locks
+}
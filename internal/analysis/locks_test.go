@@ -0,0 +1,99 @@
+package analysis_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/becheran/roumon/internal/analysis"
+	"github.com/stretchr/testify/assert"
+)
+
+var trace_hotLocks = `goroutine 1 [sync.Mutex.Lock]:
+internal/sync.runtime_SemacquireMutex(0x0?, 0x0?, 0x0?)
+	/usr/local/go/src/runtime/sema.go:95 +0x25
+internal/sync.(*Mutex).lockSlow(0xc000010000)
+	/usr/local/go/src/internal/sync/mutex.go:149 +0x15a
+internal/sync.(*Mutex).Lock(...)
+	/usr/local/go/src/internal/sync/mutex.go:70
+sync.(*Mutex).Lock(...)
+	/usr/local/go/src/sync/mutex.go:46
+main.(*Cache).Get(0xc000010000)
+	/app/cache.go:20 +0x5b
+created by main.main in goroutine 1
+	/app/main.go:11 +0xa7
+
+goroutine 2 [sync.Mutex.Lock, 3 minutes]:
+internal/sync.runtime_SemacquireMutex(0x0?, 0x0?, 0x0?)
+	/usr/local/go/src/runtime/sema.go:95 +0x25
+internal/sync.(*Mutex).lockSlow(0xc000010000)
+	/usr/local/go/src/internal/sync/mutex.go:149 +0x15a
+internal/sync.(*Mutex).Lock(...)
+	/usr/local/go/src/internal/sync/mutex.go:70
+sync.(*Mutex).Lock(...)
+	/usr/local/go/src/sync/mutex.go:46
+main.(*Cache).Get(0xc000010000)
+	/app/cache.go:20 +0x5b
+created by main.main in goroutine 1
+	/app/main.go:12 +0xa7
+
+goroutine 3 [sync.Mutex.Lock, 1 minutes]:
+internal/sync.runtime_SemacquireMutex(0x0?, 0x0?, 0x0?)
+	/usr/local/go/src/runtime/sema.go:95 +0x25
+internal/sync.(*Mutex).lockSlow(0xc000010000)
+	/usr/local/go/src/internal/sync/mutex.go:149 +0x15a
+internal/sync.(*Mutex).Lock(...)
+	/usr/local/go/src/internal/sync/mutex.go:70
+sync.(*Mutex).Lock(...)
+	/usr/local/go/src/sync/mutex.go:46
+main.(*Cache).Put(0xc000010000, {0x5c1a2e, 0x3})
+	/app/cache.go:30 +0x5b
+created by main.main in goroutine 1
+	/app/main.go:13 +0xa7
+
+goroutine 4 [sync.Mutex.Lock]:
+internal/sync.runtime_SemacquireMutex(0x0?, 0x0?, 0x0?)
+	/usr/local/go/src/runtime/sema.go:95 +0x25
+internal/sync.(*Mutex).lockSlow(0xc000020000)
+	/usr/local/go/src/internal/sync/mutex.go:149 +0x15a
+internal/sync.(*Mutex).Lock(...)
+	/usr/local/go/src/internal/sync/mutex.go:70
+sync.(*Mutex).Lock(...)
+	/usr/local/go/src/sync/mutex.go:46
+main.(*Pool).Get(0xc000020000)
+	/app/cache.go:40 +0x5b
+created by main.main in goroutine 1
+	/app/main.go:14 +0xa7
+
+goroutine 5 [sync.RWMutex.RLock]:
+sync.runtime_SemacquireRWMutexR(0x0?, 0x0?, 0x0?)
+	/usr/local/go/src/runtime/sema.go:100 +0x25
+sync.(*RWMutex).RLock(...)
+	/usr/local/go/src/sync/rwmutex.go:74
+main.(*Config).Get(...)
+	/app/config.go:30
+main.main.func3()
+	/app/main.go:30 +0x31
+created by main.main in goroutine 1
+	/app/main.go:29 +0x20b
+
+goroutine 6 [chan receive]:
+main.main()
+	/app/main.go:10 +0x1d`
+
+func TestHotLocks(t *testing.T) {
+	locks := analysis.HotLocks(parse(t, trace_hotLocks))
+	assert.Len(t, locks, 3)
+
+	assert.Equal(t, "mutex 0xc000010000", locks[0].Name())
+	assert.Equal(t, []int64{1, 2, 3}, locks[0].Waiters)
+	assert.Equal(t, 3*time.Minute, locks[0].LongestWait)
+	assert.Equal(t, "main.(*Cache).Get", locks[0].Site.FuncName)
+	assert.Equal(t, 2, locks[0].Sites)
+
+	assert.Equal(t, "mutex 0xc000020000", locks[1].Name())
+	assert.Equal(t, []int64{4}, locks[1].Waiters)
+
+	assert.Equal(t, analysis.TargetRWMutex, locks[2].Kind)
+	assert.Equal(t, "rwmutex (unknown address)", locks[2].Name())
+	assert.Equal(t, "/app/config.go:30", locks[2].Site.Location())
+}
@@ -0,0 +1,79 @@
+package analysis
+
+import (
+	"sort"
+
+	"github.com/becheran/roumon/internal/model"
+)
+
+// Site is a call site of a runtime profile such as the block profile with the summed values of all its stacks
+type Site struct {
+	Frame  model.StackFrame // First frame outside of the sync and runtime packages. Outermost frame if there is none. Zero for empty stacks
+	Values []int64          // Summed values in the order of the sample types of the profile
+	Stacks int              // Number of different stacks through the site
+}
+
+// Key identifies the site in different profiles of the same process
+func (s Site) Key() string {
+	return s.Frame.FuncName + " " + s.Frame.Location()
+}
+
+// profileSite returns the first frame outside of the sync and runtime packages. The outermost frame is returned
+// if there is none. Stacks of the threadcreate profile are often empty. The zero frame is returned in this case
+func profileSite(stack []model.StackFrame) model.StackFrame {
+	var site model.StackFrame
+	for _, f := range stack {
+		if f.FuncName == "" {
+			continue
+		}
+		if !isWaitPackage(f) {
+			return f
+		}
+		site = f
+	}
+	return site
+}
+
+// TopSites sums the samples of a profile per call site. Sites with the highest value at the index first
+func TopSites(p *model.Profile, index int) []Site {
+	var sites []Site
+	positions := make(map[string]int)
+	for _, s := range p.Samples {
+		site := Site{Frame: profileSite(s.StackTrace)}
+		i, ok := positions[site.Key()]
+		if !ok {
+			i = len(sites)
+			positions[site.Key()] = i
+			site.Values = make([]int64, len(p.SampleTypes))
+			sites = append(sites, site)
+		}
+		for j, v := range s.Values {
+			if j < len(sites[i].Values) {
+				sites[i].Values[j] += v
+			}
+		}
+		sites[i].Stacks++
+	}
+	if index >= 0 && index < len(p.SampleTypes) {
+		sort.SliceStable(sites, func(i, j int) bool {
+			return sites[i].Values[index] > sites[j].Values[index]
+		})
+	}
+	return sites
+}
+
+// BlockedIn counts the goroutines blocked on channels or locks per function of the call site.
+// Lock and unlock of a mutex are usually in the same function, so the sites of the block and mutex profiles can be matched
+func BlockedIn(routines []model.Goroutine) map[string]int {
+	blocked := make(map[string]int)
+	for _, g := range routines {
+		c := g.Status.Category()
+		if c != model.CategoryChannel && c != model.CategoryLock {
+			continue
+		}
+		if _, site, _ := waitTarget(g); site < len(g.StackTrace) {
+			blocked[g.StackTrace[site].FuncName]++
+		}
+	}
+	return blocked
+}
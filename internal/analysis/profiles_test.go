@@ -0,0 +1,48 @@
+package analysis_test
+
+import (
+	"testing"
+
+	"github.com/becheran/roumon/internal/analysis"
+	"github.com/becheran/roumon/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func frame(name string, file string, line int32) model.StackFrame {
+	return model.StackFrame{FuncName: name, Func: model.ParseFunc(name), File: file, Line: line}
+}
+
+func TestTopSites(t *testing.T) {
+	lock := frame("sync.(*Mutex).Lock", "/usr/local/go/src/sync/mutex.go", 46)
+	recv := frame("runtime.chanrecv1", "/usr/local/go/src/runtime/chan.go", 489)
+	get := frame("main.(*Cache).Get", "/app/cache.go", 20)
+	put := frame("main.(*Cache).Put", "/app/cache.go", 30)
+	profile := &model.Profile{
+		SampleTypes: []string{"contentions/count", "delay/nanoseconds"},
+		Samples: []model.Sample{
+			{Values: []int64{10, 1000}, StackTrace: []model.StackFrame{lock, get, frame("main.handler", "/app/main.go", 10)}},
+			{Values: []int64{5, 500}, StackTrace: []model.StackFrame{lock, get, frame("main.worker", "/app/main.go", 20)}},
+			{Values: []int64{100, 200}, StackTrace: []model.StackFrame{lock, put}},
+			{Values: []int64{1, 3000}, StackTrace: []model.StackFrame{recv, frame("main.wait", "/app/main.go", 30)}},
+			{Values: []int64{1, 1}, StackTrace: []model.StackFrame{frame("runtime.newm", "/usr/local/go/src/runtime/proc.go", 10), frame("runtime.main", "/usr/local/go/src/runtime/proc.go", 20)}},
+			{Values: []int64{1, 1}},
+		},
+	}
+	sites := analysis.TopSites(profile, profile.ValueIndex("delay"))
+	if assert.Len(t, sites, 5) {
+		assert.Equal(t, "main.wait", sites[0].Frame.FuncName)
+		assert.Equal(t, "main.(*Cache).Get", sites[1].Frame.FuncName)
+		assert.Equal(t, []int64{15, 1500}, sites[1].Values)
+		assert.Equal(t, 2, sites[1].Stacks)
+		assert.Equal(t, "main.(*Cache).Put /app/cache.go:30", sites[2].Key())
+		assert.Equal(t, "runtime.main", sites[3].Frame.FuncName)
+		assert.Equal(t, model.StackFrame{}, sites[4].Frame)
+		assert.Equal(t, 1, sites[4].Stacks)
+	}
+}
+
+func TestBlockedIn(t *testing.T) {
+	routines := append(parse(t, trace_hotLocks), model.Goroutine{ID: 7, Status: model.StatusRunning, StackTrace: []model.StackFrame{frame("main.(*Cache).Get", "/app/cache.go", 20)}})
+	blocked := analysis.BlockedIn(routines)
+	assert.Equal(t, map[string]int{"main.(*Cache).Get": 2, "main.(*Cache).Put": 1, "main.(*Pool).Get": 1, "main.(*Config).Get": 1, "main.main": 1}, blocked)
+}
@@ -0,0 +1,120 @@
+// Package analysis finds deadlocks, lock contention and blocked channels in goroutine dumps and runtime profiles
+package analysis
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/becheran/roumon/internal/model"
+)
+
+// TargetKind is the kind of object a goroutine waits for
+type TargetKind string
+
+// Kinds of wait targets
+const (
+	TargetMutex     TargetKind = "mutex"
+	TargetRWMutex   TargetKind = "rwmutex"
+	TargetWaitGroup TargetKind = "waitgroup"
+	TargetCond      TargetKind = "cond"
+	TargetChannel   TargetKind = "channel"
+)
+
+// waitFunc is a function which blocks on a lock, wait group or channel
+type waitFunc struct {
+	kind      TargetKind
+	hasObject bool // First argument is the address of the object
+}
+
+// Functions which block goroutines. Internal semaphore functions only receive the address of a field of the object
+var waitFuncs = map[string]waitFunc{
+	"sync.(*Mutex).Lock":                    {TargetMutex, true},
+	"sync.(*Mutex).lockSlow":                {TargetMutex, true},
+	"internal/sync.(*Mutex).Lock":           {TargetMutex, true},
+	"internal/sync.(*Mutex).lockSlow":       {TargetMutex, true},
+	"sync.runtime_SemacquireMutex":          {TargetMutex, false},
+	"internal/sync.runtime_SemacquireMutex": {TargetMutex, false},
+	"sync.(*RWMutex).Lock":                  {TargetRWMutex, true},
+	"sync.(*RWMutex).RLock":                 {TargetRWMutex, true},
+	"sync.runtime_SemacquireRWMutex":        {TargetRWMutex, false},
+	"sync.runtime_SemacquireRWMutexR":       {TargetRWMutex, false},
+	"sync.(*WaitGroup).Wait":                {TargetWaitGroup, true},
+	"sync.runtime_SemacquireWaitGroup":      {TargetWaitGroup, false},
+	"sync.(*Cond).Wait":                     {TargetCond, true},
+	"sync.runtime_notifyListWait":           {TargetCond, false},
+	"runtime.chansend1":                     {TargetChannel, true},
+	"runtime.chansend":                      {TargetChannel, true},
+	"runtime.chanrecv1":                     {TargetChannel, true},
+	"runtime.chanrecv2":                     {TargetChannel, true},
+	"runtime.chanrecv":                      {TargetChannel, true},
+}
+
+// Target is the object a blocked goroutine waits for
+type Target struct {
+	Kind    TargetKind
+	Address uint64           // Address of the object. Zero if not shown in the dump, for example for inlined frames
+	Site    model.StackFrame // First frame outside of the sync and runtime packages which waits for the object
+}
+
+// ParsePointer parses a frame argument such as 0xc000012345. Values which are elided (...),
+// possibly inaccurate (0x1?) or zero are rejected
+func ParsePointer(arg string) (uint64, bool) {
+	if strings.HasSuffix(arg, "?") || !strings.HasPrefix(arg, "0x") {
+		return 0, false
+	}
+	v, err := strconv.ParseUint(arg[2:], 16, 64)
+	return v, err == nil && v != 0
+}
+
+// isWaitPackage returns true for packages which implement blocking on locks and channels
+func isWaitPackage(f model.StackFrame) bool {
+	switch f.Func.ImportPath {
+	case "sync", "internal/sync", "runtime":
+		return true
+	}
+	return false
+}
+
+// WaitTarget returns the lock, wait group or channel a goroutine waits for.
+// Returns false if the goroutine does not wait or the object is not part of the stack
+func WaitTarget(g model.Goroutine) (Target, bool) {
+	target, _, ok := waitTarget(g)
+	return target, ok
+}
+
+// waitTarget returns the wait target and the index of the wait site in the stack. The index is the length of the stack if there is no site
+func waitTarget(g model.Goroutine) (target Target, site int, found bool) {
+	for site = 0; site < len(g.StackTrace); site++ {
+		f := g.StackTrace[site]
+		if !isWaitPackage(f) {
+			break
+		}
+		wf, ok := waitFuncs[f.FuncName]
+		if !ok {
+			continue
+		}
+		// The outermost function is the most specific one, such as sync.(*WaitGroup).Wait for a semaphore
+		target.Kind = wf.kind
+		found = true
+		if wf.hasObject && target.Address == 0 && len(f.Args) > 0 {
+			target.Address, _ = ParsePointer(f.Args[0])
+		}
+	}
+	if found && site < len(g.StackTrace) {
+		target.Site = g.StackTrace[site]
+	}
+	return target, site, found
+}
+
+// references returns the addresses of the reliable pointer arguments of the frames from the index on
+func references(g model.Goroutine, from int) map[uint64]bool {
+	refs := make(map[uint64]bool)
+	for _, f := range g.StackTrace[min(from, len(g.StackTrace)):] {
+		for _, arg := range f.Args {
+			if v, ok := ParsePointer(arg); ok {
+				refs[v] = true
+			}
+		}
+	}
+	return refs
+}
@@ -0,0 +1,299 @@
+// Package baseline checks goroutine dumps against a reviewed list of expected stack groups
+package baseline
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/becheran/roumon/internal/model"
+)
+
+// Unbounded is the maximum of an entry without an upper limit
+const Unbounded = -1
+
+// header is written at the top of every baseline file
+const header = `# roumon baseline
+# One expected stack group per line: <fingerprint> <min>..<max> <name>
+# The count range is inclusive. Use * as max for no upper limit.
+# The name and the commented stacks are informational only.
+`
+
+// Entry is one expected stack group
+type Entry struct {
+	Fingerprint string // See model.Fingerprint
+	Min         int
+	Max         int      // Unbounded for no upper limit
+	Name        string   // Informational name of the stack
+	Funcs       []string // Functions of the stack. Only set by FromGroups
+}
+
+// Range returns the count range such as 40..40 or 1..*
+func (e Entry) Range() string {
+	if e.Max == Unbounded {
+		return fmt.Sprintf("%d..*", e.Min)
+	}
+	return fmt.Sprintf("%d..%d", e.Min, e.Max)
+}
+
+// Allows returns true if the count is within the range of the entry
+func (e Entry) Allows(count int) bool {
+	return count >= e.Min && (e.Max == Unbounded || count <= e.Max)
+}
+
+// Baseline is the list of expected stack groups of a service
+type Baseline struct {
+	Entries []Entry
+}
+
+// Load reads a baseline file
+func Load(path string) (*Baseline, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baseline %s. Err: %s", path, err.Error())
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			log.Printf("Error while closing baseline %s: %s", path, err.Error())
+		}
+	}()
+	b, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("invalid baseline %s. Err: %s", path, err.Error())
+	}
+	return b, nil
+}
+
+// Parse reads a baseline. Empty lines and lines starting with # are ignored
+func Parse(reader io.Reader) (*Baseline, error) {
+	b := &Baseline{}
+	seen := make(map[string]int)
+	scanner := bufio.NewScanner(reader)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		e, err := parseEntry(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", lineNumber, err.Error())
+		}
+		if prev, ok := seen[e.Fingerprint]; ok {
+			return nil, fmt.Errorf("line %d: fingerprint %s already defined in line %d", lineNumber, e.Fingerprint, prev)
+		}
+		seen[e.Fingerprint] = lineNumber
+		b.Entries = append(b.Entries, e)
+	}
+	return b, scanner.Err()
+}
+
+// parseEntry parses a line such as 3f2a9c1e8b7d6a5f 40..40 main.worker
+func parseEntry(line string) (e Entry, err error) {
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return e, fmt.Errorf("expected entry of form \"<fingerprint> <min>..<max> <name>\", but got: %s", line)
+	}
+	e.Fingerprint = fields[0]
+	if _, err := strconv.ParseUint(e.Fingerprint, 16, 64); err != nil || len(e.Fingerprint) != 16 {
+		return e, fmt.Errorf("invalid fingerprint %s. Expected 16 hex digits", e.Fingerprint)
+	}
+	minText, maxText, found := strings.Cut(fields[1], "..")
+	if !found {
+		return e, fmt.Errorf("expected count range of form <min>..<max>, but got: %s", fields[1])
+	}
+	if e.Min, err = strconv.Atoi(minText); err != nil || e.Min < 0 {
+		return e, fmt.Errorf("invalid minimum count %s", minText)
+	}
+	if maxText == "*" {
+		e.Max = Unbounded
+	} else if e.Max, err = strconv.Atoi(maxText); err != nil || e.Max < e.Min {
+		return e, fmt.Errorf("invalid maximum count %s", maxText)
+	}
+	e.Name = strings.Join(fields[2:], " ")
+	return e, nil
+}
+
+// Write writes the baseline in the format read by Parse
+func (b *Baseline) Write(w io.Writer) error {
+	var sb strings.Builder
+	sb.WriteString(header)
+	for _, e := range b.Entries {
+		fmt.Fprintf(&sb, "\n%s %s %s\n", e.Fingerprint, e.Range(), e.Name)
+		for _, f := range e.Funcs {
+			fmt.Fprintf(&sb, "#   %s\n", f)
+		}
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// Save writes the baseline to a file
+func (b *Baseline) Save(path string) (err error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create baseline %s. Err: %s", path, err.Error())
+	}
+	defer func() {
+		if closeErr := f.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("failed to close baseline %s. Err: %s", path, closeErr.Error())
+		}
+	}()
+	if err = b.Write(f); err != nil {
+		return fmt.Errorf("failed to write baseline %s. Err: %s", path, err.Error())
+	}
+	return nil
+}
+
+// Name returns the short name of the first own frame of the stack or of the top frame
+func Name(stack []model.StackFrame, c model.Classifier) string {
+	frame, ok := c.NameFrame(stack)
+	if !ok {
+		return "(no frames)"
+	}
+	return frame.ShortName()
+}
+
+// fingerprintGroup is the sum of all groups with the same fingerprint
+type fingerprintGroup struct {
+	fingerprint string
+	count       int
+	stack       []model.StackFrame
+}
+
+// groupByFingerprint sums the counts of groups with the same fingerprint. Order of first occurrence
+func groupByFingerprint(groups []model.Group) []fingerprintGroup {
+	var result []fingerprintGroup
+	index := make(map[string]int)
+	for _, g := range groups {
+		fp := model.Fingerprint(g.StackTrace)
+		i, ok := index[fp]
+		if !ok {
+			i = len(result)
+			index[fp] = i
+			result = append(result, fingerprintGroup{fingerprint: fp, stack: g.StackTrace})
+		}
+		result[i].count += g.Count
+	}
+	return result
+}
+
+// FromGroups creates a baseline which allows exactly the counts of the groups. Largest groups first
+func FromGroups(groups []model.Group, c model.Classifier) *Baseline {
+	fpGroups := groupByFingerprint(groups)
+	sort.SliceStable(fpGroups, func(i, j int) bool {
+		return fpGroups[i].count > fpGroups[j].count
+	})
+	b := &Baseline{Entries: make([]Entry, len(fpGroups))}
+	for i, g := range fpGroups {
+		funcs := make([]string, len(g.stack))
+		for j, f := range g.stack {
+			funcs[j] = f.FuncName
+		}
+		b.Entries[i] = Entry{Fingerprint: g.fingerprint, Min: g.count, Max: g.count, Name: Name(g.stack, c), Funcs: funcs}
+	}
+	return b
+}
+
+// Kind of a baseline violation
+type Kind string
+
+// Kinds of violations
+const (
+	Unexpected Kind = "unexpected" // Stack is not part of the baseline
+	TooMany    Kind = "too many"
+	TooFew     Kind = "too few"
+)
+
+// Violation is a stack group whose count is not allowed by the baseline
+type Violation struct {
+	Kind       Kind
+	Entry      Entry // Not set for Unexpected
+	Count      int
+	StackTrace []model.StackFrame // Not set if no goroutine with the stack exists
+}
+
+// Check returns all stack groups which are not covered by the baseline. Unexpected stacks first, then by count descending
+func (b *Baseline) Check(groups []model.Group) []Violation {
+	entries := make(map[string]Entry, len(b.Entries))
+	for _, e := range b.Entries {
+		entries[e.Fingerprint] = e
+	}
+	var violations []Violation
+	found := make(map[string]bool)
+	for _, g := range groupByFingerprint(groups) {
+		found[g.fingerprint] = true
+		e, ok := entries[g.fingerprint]
+		switch {
+		case !ok:
+			violations = append(violations, Violation{Kind: Unexpected, Count: g.count, StackTrace: g.stack})
+		case g.count > e.Max && e.Max != Unbounded:
+			violations = append(violations, Violation{Kind: TooMany, Entry: e, Count: g.count, StackTrace: g.stack})
+		case g.count < e.Min:
+			violations = append(violations, Violation{Kind: TooFew, Entry: e, Count: g.count, StackTrace: g.stack})
+		}
+	}
+	for _, e := range b.Entries {
+		if !found[e.Fingerprint] && e.Min > 0 {
+			violations = append(violations, Violation{Kind: TooFew, Entry: e})
+		}
+	}
+	sort.SliceStable(violations, func(i, j int) bool {
+		if (violations[i].Kind == Unexpected) != (violations[j].Kind == Unexpected) {
+			return violations[i].Kind == Unexpected
+		}
+		return violations[i].Count > violations[j].Count
+	})
+	return violations
+}
+
+// Uncovered returns the fingerprints of all stacks of the violations
+func Uncovered(violations []Violation) map[string]Violation {
+	result := make(map[string]Violation, len(violations))
+	for _, v := range violations {
+		if v.StackTrace != nil {
+			result[model.Fingerprint(v.StackTrace)] = v
+		}
+	}
+	return result
+}
+
+// String returns a short description such as "unexpected 3x main.worker"
+func (v Violation) String(c model.Classifier) string {
+	name := v.Entry.Name
+	if v.StackTrace != nil {
+		name = Name(v.StackTrace, c)
+	}
+	if v.Kind == Unexpected {
+		return fmt.Sprintf("unexpected %dx %s", v.Count, name)
+	}
+	return fmt.Sprintf("%s %dx %s (expected %s)", v.Kind, v.Count, name, v.Entry.Range())
+}
+
+// WriteViolations writes one line per violation. The full stacks are included if stacks is set
+func WriteViolations(w io.Writer, violations []Violation, c model.Classifier, stacks bool) error {
+	var sb strings.Builder
+	for _, v := range violations {
+		fingerprint := v.Entry.Fingerprint
+		if v.StackTrace != nil {
+			fingerprint = model.Fingerprint(v.StackTrace)
+		}
+		fmt.Fprintf(&sb, "%s %s\n", fingerprint, v.String(c))
+		if stacks {
+			for _, f := range v.StackTrace {
+				fmt.Fprintf(&sb, "    %s\n        %s\n", f.FuncName, f.Location())
+			}
+			if len(v.StackTrace) > 0 {
+				sb.WriteByte('\n')
+			}
+		}
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
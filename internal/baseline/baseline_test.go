@@ -0,0 +1,111 @@
+package baseline_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/becheran/roumon/internal/baseline"
+	"github.com/becheran/roumon/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func group(count int, funcs ...string) model.Group {
+	g := model.Group{Count: count}
+	for i, f := range funcs {
+		g.StackTrace = append(g.StackTrace, model.StackFrame{FuncName: f, File: "/app/main.go", Line: int32(10 + i)})
+	}
+	return g
+}
+
+func TestFromGroupsRoundTrip(t *testing.T) {
+	groups := []model.Group{
+		group(2, "main.serve", "main.main"),
+		group(40, "main.worker", "main.main"),
+		group(1, "main.serve", "main.main"),
+	}
+	b := baseline.FromGroups(groups, model.NewClassifier("main"))
+	assert.Len(t, b.Entries, 2)
+	assert.Equal(t, "main.worker", b.Entries[0].Name)
+	assert.Equal(t, "40..40", b.Entries[0].Range())
+	assert.Equal(t, "3..3", b.Entries[1].Range())
+	assert.Equal(t, []string{"main.serve", "main.main"}, b.Entries[1].Funcs)
+
+	path := filepath.Join(t.TempDir(), "baseline.txt")
+	assert.Nil(t, b.Save(path))
+	loaded, err := baseline.Load(path)
+	assert.Nil(t, err)
+	assert.Len(t, loaded.Entries, 2)
+	assert.Equal(t, b.Entries[0].Fingerprint, loaded.Entries[0].Fingerprint)
+	assert.Equal(t, "main.worker", loaded.Entries[0].Name)
+	assert.Equal(t, 40, loaded.Entries[0].Min)
+	assert.Equal(t, 40, loaded.Entries[0].Max)
+	assert.Nil(t, loaded.Entries[0].Funcs)
+
+	data, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), "#   main.worker\n#   main.main\n")
+}
+
+func TestParse(t *testing.T) {
+	b, err := baseline.Parse(strings.NewReader("# comment\n\n0123456789abcdef 1..* main.serve\n  fedcba9876543210   0..2\n"))
+	assert.Nil(t, err)
+	assert.Len(t, b.Entries, 2)
+	assert.Equal(t, baseline.Unbounded, b.Entries[0].Max)
+	assert.True(t, b.Entries[0].Allows(1000))
+	assert.False(t, b.Entries[0].Allows(0))
+	assert.Equal(t, "", b.Entries[1].Name)
+	assert.True(t, b.Entries[1].Allows(0))
+	assert.False(t, b.Entries[1].Allows(3))
+
+	for _, invalid := range []string{
+		"0123456789abcdef",
+		"0123456789abcdeg 1..2",
+		"0123 1..2",
+		"0123456789abcdef 1-2",
+		"0123456789abcdef x..2",
+		"0123456789abcdef 3..2",
+		"0123456789abcdef 1..2\n0123456789abcdef 1..3",
+	} {
+		_, err := baseline.Parse(strings.NewReader(invalid))
+		assert.NotNil(t, err, invalid)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	classifier := model.NewClassifier("main")
+	b := baseline.FromGroups([]model.Group{
+		group(40, "main.worker", "main.main"),
+		group(3, "main.serve", "main.main"),
+		group(1, "main.flush", "main.main"),
+	}, classifier)
+	b.Entries[1].Min = 1
+	b.Entries[1].Max = baseline.Unbounded
+
+	assert.Empty(t, b.Check([]model.Group{group(40, "main.worker", "main.main"), group(10, "main.serve", "main.main"), group(1, "main.flush", "main.main")}))
+
+	violations := b.Check([]model.Group{
+		group(41, "main.worker", "main.main"),
+		group(5, "main.leak", "main.main"),
+		group(1, "main.serve", "main.main"),
+	})
+	assert.Len(t, violations, 3)
+	assert.Equal(t, baseline.Unexpected, violations[0].Kind)
+	assert.Equal(t, 5, violations[0].Count)
+	assert.Equal(t, "unexpected 5x main.leak", violations[0].String(classifier))
+	assert.Equal(t, baseline.TooMany, violations[1].Kind)
+	assert.Equal(t, "too many 41x main.worker (expected 40..40)", violations[1].String(classifier))
+	assert.Equal(t, baseline.TooFew, violations[2].Kind)
+	assert.Equal(t, 0, violations[2].Count)
+	assert.Nil(t, violations[2].StackTrace)
+	assert.Equal(t, "too few 0x main.flush (expected 1..1)", violations[2].String(classifier))
+
+	uncovered := baseline.Uncovered(violations)
+	assert.Len(t, uncovered, 2)
+	assert.Contains(t, uncovered, model.Fingerprint(group(1, "main.leak", "main.main").StackTrace))
+
+	var sb strings.Builder
+	assert.Nil(t, baseline.WriteViolations(&sb, violations[:1], classifier, true))
+	assert.Equal(t, model.Fingerprint(violations[0].StackTrace)+" unexpected 5x main.leak\n    main.leak\n        /app/main.go:10\n    main.main\n        /app/main.go:11\n\n", sb.String())
+}
@@ -2,52 +2,309 @@ package client
 
 import (
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/becheran/roumon/internal/model"
 )
 
+// Goroutine profile formats
+const (
+	FormatStacks  = "stacks"  // One stack per goroutine with ID and status (debug=2)
+	FormatGrouped = "grouped" // Identical stacks grouped with count and labels (debug=1). Cheaper for many goroutines
+	FormatProto   = "proto"   // Gzipped protobuf profile with count and labels per stack (debug=0)
+)
+
+// Runtime profiles which can be fetched in addition to the goroutine profile.
+// The block and mutex profiles are only recorded if enabled with runtime.SetBlockProfileRate and runtime.SetMutexProfileFraction
+const (
+	ProfileBlock        = "block"        // Stacks which blocked on channels and locks
+	ProfileMutex        = "mutex"        // Stacks which held contended mutexes
+	ProfileThreadCreate = "threadcreate" // Stacks which created OS threads
+)
+
+// Profiles lists all additional runtime profiles
+var Profiles = []string{ProfileBlock, ProfileMutex, ProfileThreadCreate}
+
+// Default intervals of scraping the goroutines and fetching the additional runtime profiles
+const (
+	DefaultInterval        = time.Second
+	DefaultProfileInterval = 10 * time.Second
+)
+
+// Timeout of each request to the pprof server
+const requestTimeout = 30 * time.Second
+
+// ParseProfiles parses a comma separated list of additional runtime profiles such as block,mutex
+func ParseProfiles(list string) ([]string, error) {
+	var profiles []string
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !slices.Contains(Profiles, name) {
+			return nil, fmt.Errorf("invalid profile %q. Must be one of %s", name, strings.Join(Profiles, ", "))
+		}
+		if !slices.Contains(profiles, name) {
+			profiles = append(profiles, name)
+		}
+	}
+	return profiles, nil
+}
+
 // Client for pprof events
 type Client struct {
-	c      *http.Client
-	server string
+	c               *http.Client
+	base            string // URL of the pprof index
+	vars            string // URL of the expvar endpoint
+	server          string
+	Interval        time.Duration // Interval of scraping the goroutines. Defaults to DefaultInterval
+	Strict          bool          // Fail the whole scrape if a part of the dump cannot be parsed
+	Format          string        // One of FormatStacks, FormatGrouped or FormatProto. Defaults to FormatStacks
+	Profiles        []string      // Additional runtime profiles such as ProfileBlock. None are fetched if empty
+	ProfileInterval time.Duration // Interval of fetching the additional profiles and metrics. Defaults to DefaultProfileInterval
+	Metrics         string        // Source of runtime metrics such as MetricsHeap. None are fetched if empty
+}
+
+// Scrape is the result of one request to the pprof server
+type Scrape struct {
+	URL         string
+	Time        time.Time
+	Err         error // Set if the request failed. Routines are not set in this case
+	Latency     time.Duration
+	Size        int64               // Size of the goroutine dump in bytes
+	Routines    []model.Goroutine   // Set for FormatStacks
+	Groups      []model.Group       // Set for FormatGrouped and FormatProto
+	ParseErrors []*model.ParseError // Skipped lines which could not be parsed
+	Profiles    []ProfileScrape     // Additional runtime profiles. Only set for scrapes after each profile interval
+	Metrics     *Metrics            // Runtime metrics. Only set for scrapes after each profile interval if the request succeeded
+	MetricsErr  error               // Set for scrapes after each profile interval if the runtime metrics could not be fetched
+}
+
+// ProfileScrape is the result of fetching one additional runtime profile
+type ProfileScrape struct {
+	Name    string // One of Profiles
+	URL     string
+	Time    time.Time
+	Err     error          // Set if the request failed. Profile is not set in this case
+	Profile *model.Profile // Cumulative values since the start of the process
+}
+
+// Total returns the number of goroutines of the scrape
+func (s Scrape) Total() int {
+	if s.Groups != nil {
+		return model.Total(s.Groups)
+	}
+	return len(s.Routines)
 }
 
 // NewClient creates a new client listening for pprof events
 func NewClient(ip string, port int) *Client {
-	server := fmt.Sprintf("http://%s:%d/debug/pprof/goroutine?debug=2", ip, port)
+	base := fmt.Sprintf("http://%s:%d/debug/pprof/", ip, port)
+	server := base + "goroutine"
 	log.Printf("Attach to server %s\n", server)
-	c := &http.Client{}
+	c := &http.Client{Timeout: requestTimeout}
 	return &Client{
 		c:      c,
+		base:   base,
+		vars:   fmt.Sprintf("http://%s:%d/debug/vars", ip, port),
 		server: server,
 	}
 }
 
-// Run starts the client and listen for incoming routine changes
-func (client *Client) Run(terminate chan<- error, routineUpdate chan<- []model.Goroutine) {
-	ticker := time.NewTicker(time.Second * 1)
-	defer ticker.Stop()
+// countingReader counts the bytes read from the underlying reader
+type countingReader struct {
+	r io.Reader
+	n int64
+}
 
-	for {
-		resp, err := client.c.Get(client.server)
-		if err != nil {
-			terminate <- fmt.Errorf("failed to list go routines. Err: %s", err.Error())
-			return
-		}
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// scrape requests and parses the goroutines once
+func (client *Client) scrape() Scrape {
+	start := time.Now()
+	scrape := Scrape{
+		URL:  client.server + "?debug=2",
+		Time: start,
+	}
+	switch client.Format {
+	case FormatGrouped:
+		scrape.URL = client.server + "?debug=1"
+	case FormatProto:
+		scrape.URL = client.server + "?debug=0"
+	}
 
-		goroutines, err := model.ParseStackFrame(resp.Body)
-		if err != nil {
-			log.Printf("Error while parsing stack: %s", err.Error())
-		} else {
-			routineUpdate <- goroutines
+	resp, err := client.c.Get(scrape.URL)
+	if err != nil {
+		scrape.Err = fmt.Errorf("failed to list go routines. Err: %s", err.Error())
+		return scrape
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Printf("Error while closing response body: %s", err.Error())
 		}
+	}()
+	if resp.StatusCode != http.StatusOK {
+		scrape.Err = fmt.Errorf("failed to list go routines. Status: %s", resp.Status)
+		return scrape
+	}
 
+	body := &countingReader{r: resp.Body}
+	var goroutines []model.Goroutine
+	var groups []model.Group
+	var skipped []*model.ParseError
+	switch {
+	case client.Format == FormatProto:
+		// Binary profiles are decoded completely or not at all
+		groups, err = model.ParseProfile(body)
+	case client.Format == FormatGrouped && client.Strict:
+		groups, err = model.ParseGroupsStrict(body)
+	case client.Format == FormatGrouped:
+		groups, skipped, err = model.ParseGroupsErrors(body)
+	case client.Strict:
+		goroutines, err = model.ParseStackFrameStrict(body)
+	default:
+		goroutines, skipped, err = model.ParseStackFrameErrors(body)
+	}
+	scrape.Latency = time.Since(start)
+	scrape.Size = body.n
+	if err != nil {
+		scrape.Err = fmt.Errorf("failed to parse stack. Err: %s", err.Error())
+		return scrape
+	}
+	for _, e := range skipped {
+		log.Print(e.Error())
+	}
+	scrape.Routines = goroutines
+	scrape.Groups = groups
+	if client.Format != FormatStacks && client.Format != "" && groups == nil {
+		scrape.Groups = []model.Group{}
+	}
+	scrape.ParseErrors = skipped
+	return scrape
+}
+
+// scrapeProfile requests and parses one additional runtime profile in the protobuf format
+func (client *Client) scrapeProfile(name string) ProfileScrape {
+	scrape := ProfileScrape{
+		Name: name,
+		URL:  client.base + name,
+		Time: time.Now(),
+	}
+	resp, err := client.c.Get(scrape.URL)
+	if err != nil {
+		scrape.Err = fmt.Errorf("failed to fetch %s profile. Err: %s", name, err.Error())
+		return scrape
+	}
+	defer func() {
 		if err := resp.Body.Close(); err != nil {
 			log.Printf("Error while closing response body: %s", err.Error())
 		}
+	}()
+	if resp.StatusCode != http.StatusOK {
+		scrape.Err = fmt.Errorf("failed to fetch %s profile. Status: %s", name, resp.Status)
+		return scrape
+	}
+	profile, err := model.ParseSampleProfile(resp.Body)
+	if err != nil {
+		scrape.Err = fmt.Errorf("failed to parse %s profile. Err: %s", name, err.Error())
+		return scrape
+	}
+	scrape.Profile = profile
+	return scrape
+}
+
+// ScrapeOnce requests and parses the goroutines once
+func (client *Client) ScrapeOnce() Scrape {
+	return client.scrape()
+}
+
+// interval returns the interval of scraping the goroutines
+func (client *Client) interval() time.Duration {
+	if client.Interval <= 0 {
+		return DefaultInterval
+	}
+	return client.Interval
+}
+
+// profileInterval returns the interval of fetching the additional profiles and metrics
+func (client *Client) profileInterval() time.Duration {
+	if client.ProfileInterval <= 0 {
+		return DefaultProfileInterval
+	}
+	return client.ProfileInterval
+}
+
+// every calls fetch immediately and then after each interval. Never returns
+func every(interval time.Duration, fetch func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		fetch()
+		<-ticker.C
+	}
+}
+
+// Run starts the client and listen for incoming routine changes.
+// Failed requests are reported as scrape with error and retried. Never returns
+func (client *Client) Run(routineUpdate chan<- Scrape) {
+	ticker := time.NewTicker(client.interval())
+	defer ticker.Stop()
+
+	// Runtime profiles and metrics are larger and change slower than the goroutine profile. They are fetched in the background,
+	// so a slow endpoint does not delay the goroutine scrapes, and attached to the next scrape
+	var mu sync.Mutex
+	var profiles []ProfileScrape
+	var metrics *Metrics
+	var metricsErr error
+	if len(client.Profiles) > 0 {
+		go every(client.profileInterval(), func() {
+			fetched := make([]ProfileScrape, 0, len(client.Profiles))
+			for _, name := range client.Profiles {
+				p := client.scrapeProfile(name)
+				if p.Err != nil {
+					log.Print(p.Err.Error())
+				}
+				fetched = append(fetched, p)
+			}
+			mu.Lock()
+			profiles = fetched
+			mu.Unlock()
+		})
+	}
+	if client.Metrics != "" {
+		go every(client.profileInterval(), func() {
+			m, err := client.scrapeMetrics()
+			if err != nil {
+				log.Print(err.Error())
+			}
+			mu.Lock()
+			metrics, metricsErr = m, err
+			mu.Unlock()
+		})
+	}
+
+	for {
+		scrape := client.scrape()
+		if scrape.Err != nil {
+			log.Print(scrape.Err.Error())
+		}
+		mu.Lock()
+		scrape.Profiles, profiles = profiles, nil
+		scrape.Metrics, metrics = metrics, nil
+		scrape.MetricsErr, metricsErr = metricsErr, nil
+		mu.Unlock()
+		routineUpdate <- scrape
 		<-ticker.C
 	}
 }
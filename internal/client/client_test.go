@@ -1,35 +1,283 @@
 package client_test
 
 import (
+	"expvar"
 	"fmt"
 	"log"
 	"net/http"
+	"runtime/pprof"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/becheran/roumon/internal/client"
-	"github.com/becheran/roumon/internal/model"
 	"github.com/stretchr/testify/assert"
 )
 
+// nextScrape waits for the first scrape which matches
+func nextScrape(testClient *client.Client, match func(s client.Scrape) bool) client.Scrape {
+	scrapes := make(chan client.Scrape)
+
+	go testClient.Run(scrapes)
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case s := <-scrapes:
+			// Server might not be ready yet
+			if match(s) {
+				return s
+			}
+		case <-timeout:
+			log.Fatal("Timeout")
+		}
+	}
+}
+
 func TestEmptyResponse(t *testing.T) {
 	const testport = 6062
 
 	// test server
 	go func() {
-		err := http.ListenAndServe(fmt.Sprintf("localhost:%d", testport), nil)
+		mux := http.NewServeMux()
+		mux.HandleFunc("/debug/pprof/goroutine", func(w http.ResponseWriter, r *http.Request) {})
+		err := http.ListenAndServe(fmt.Sprintf("localhost:%d", testport), mux)
+		assert.Nil(t, err)
+	}()
+
+	s := nextScrape(client.NewClient("localhost", testport), func(s client.Scrape) bool { return s.Err == nil })
+	assert.Empty(t, s.Routines)
+	assert.Equal(t, int64(0), s.Size)
+	assert.Equal(t, fmt.Sprintf("http://localhost:%d/debug/pprof/goroutine?debug=2", testport), s.URL)
+}
+
+func TestScrapeStats(t *testing.T) {
+	const testport = 6063
+	const dump = "goroutine 1 [running]:\nmain.main()\n\t/tmp/main.go:10 +0x1d\n\ninvalid\n"
+
+	go func() {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/debug/pprof/goroutine", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(dump))
+		})
+		err := http.ListenAndServe(fmt.Sprintf("localhost:%d", testport), mux)
+		assert.Nil(t, err)
+	}()
+
+	s := nextScrape(client.NewClient("localhost", testport), func(s client.Scrape) bool { return s.Err == nil })
+	assert.Len(t, s.Routines, 1)
+	assert.Equal(t, int64(len(dump)), s.Size)
+	assert.Len(t, s.ParseErrors, 1)
+	assert.Equal(t, 5, s.ParseErrors[0].Line)
+	assert.True(t, s.Latency > 0)
+}
+
+func TestStrict(t *testing.T) {
+	const testport = 6065
+	const dump = "goroutine 1 [running]:\nmain.main()\n\tinvalid\n"
+
+	go func() {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/debug/pprof/goroutine", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(dump))
+		})
+		err := http.ListenAndServe(fmt.Sprintf("localhost:%d", testport), mux)
+		assert.Nil(t, err)
+	}()
+
+	testClient := client.NewClient("localhost", testport)
+	testClient.Strict = true
+	scrapes := make(chan client.Scrape)
+	go testClient.Run(scrapes)
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case s := <-scrapes:
+			if s.Err != nil && strings.Contains(s.Err.Error(), "parse") {
+				assert.Contains(t, s.Err.Error(), "line 3")
+				assert.Empty(t, s.Routines)
+				return
+			}
+		case <-timeout:
+			log.Fatal("Timeout")
+		}
+	}
+}
+
+func TestNotFound(t *testing.T) {
+	const testport = 6064
+
+	go func() {
+		err := http.ListenAndServe(fmt.Sprintf("localhost:%d", testport), http.NewServeMux())
+		assert.Nil(t, err)
+	}()
+
+	s := nextScrape(client.NewClient("localhost", testport), func(s client.Scrape) bool {
+		return s.Err != nil && strings.Contains(s.Err.Error(), "Status")
+	})
+	assert.Contains(t, s.Err.Error(), "404")
+	assert.Empty(t, s.Routines)
+}
+
+func TestGroupedFormat(t *testing.T) {
+	const testport = 6066
+	const profile = "goroutine profile: total 3\n3 @ 0x1 0x2\n# labels: {\"a\":\"b\"}\n#\t0x1\tmain.main+0x3d\t/tmp/main.go:10\n\n"
+
+	go func() {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/debug/pprof/goroutine", func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("debug") != "1" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			_, _ = w.Write([]byte(profile))
+		})
+		err := http.ListenAndServe(fmt.Sprintf("localhost:%d", testport), mux)
+		assert.Nil(t, err)
+	}()
+
+	testClient := client.NewClient("localhost", testport)
+	testClient.Format = client.FormatGrouped
+	s := nextScrape(testClient, func(s client.Scrape) bool { return s.Err == nil })
+	assert.Equal(t, fmt.Sprintf("http://localhost:%d/debug/pprof/goroutine?debug=1", testport), s.URL)
+	assert.Empty(t, s.Routines)
+	assert.Len(t, s.Groups, 1)
+	assert.Equal(t, 3, s.Total())
+	assert.Equal(t, map[string]string{"a": "b"}, s.Groups[0].Labels)
+}
+
+func TestProtoFormat(t *testing.T) {
+	const testport = 6067
+
+	go func() {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/debug/pprof/goroutine", func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("debug") != "0" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			_ = pprof.Lookup("goroutine").WriteTo(w, 0)
+		})
+		err := http.ListenAndServe(fmt.Sprintf("localhost:%d", testport), mux)
 		assert.Nil(t, err)
 	}()
 
 	testClient := client.NewClient("localhost", testport)
+	testClient.Format = client.FormatProto
+	s := nextScrape(testClient, func(s client.Scrape) bool { return s.Err == nil })
+	assert.Equal(t, fmt.Sprintf("http://localhost:%d/debug/pprof/goroutine?debug=0", testport), s.URL)
+	assert.NotEmpty(t, s.Groups)
+	assert.True(t, s.Total() > 0)
+}
+
+func TestProfiles(t *testing.T) {
+	const testport = 6068
 
-	done := make(chan error)
-	routines := make(chan []model.Goroutine)
+	go func() {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/debug/pprof/goroutine", func(w http.ResponseWriter, r *http.Request) {})
+		mux.HandleFunc("/debug/pprof/block", func(w http.ResponseWriter, r *http.Request) {
+			_ = pprof.Lookup("block").WriteTo(w, 0)
+		})
+		err := http.ListenAndServe(fmt.Sprintf("localhost:%d", testport), mux)
+		assert.Nil(t, err)
+	}()
 
-	go testClient.Run(done, routines)
-	select {
-	case r := <-routines:
-		assert.Empty(t, r)
-	case <-done:
-		log.Fatal("Failed")
+	testClient := client.NewClient("localhost", testport)
+	testClient.Profiles = []string{client.ProfileBlock, client.ProfileMutex}
+	testClient.ProfileInterval = 100 * time.Millisecond
+	s := nextScrape(testClient, func(s client.Scrape) bool { return len(s.Profiles) > 0 && s.Profiles[0].Err == nil })
+	if assert.Len(t, s.Profiles, 2) {
+		assert.Equal(t, client.ProfileBlock, s.Profiles[0].Name)
+		assert.Equal(t, fmt.Sprintf("http://localhost:%d/debug/pprof/block", testport), s.Profiles[0].URL)
+		assert.Nil(t, s.Profiles[0].Err)
+		if assert.NotNil(t, s.Profiles[0].Profile) {
+			assert.Equal(t, []string{"contentions/count", "delay/nanoseconds"}, s.Profiles[0].Profile.SampleTypes)
+		}
+		assert.Equal(t, client.ProfileMutex, s.Profiles[1].Name)
+		assert.NotNil(t, s.Profiles[1].Err)
+		assert.Nil(t, s.Profiles[1].Profile)
 	}
 }
+
+func TestSlowProfile(t *testing.T) {
+	const testport = 6061
+	hang := make(chan struct{})
+	defer close(hang)
+
+	go func() {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/debug/pprof/goroutine", func(w http.ResponseWriter, r *http.Request) {})
+		mux.HandleFunc("/debug/pprof/block", func(w http.ResponseWriter, r *http.Request) {
+			<-hang
+		})
+		mux.HandleFunc("/debug/pprof/heap", func(w http.ResponseWriter, r *http.Request) {
+			<-hang
+		})
+		err := http.ListenAndServe(fmt.Sprintf("localhost:%d", testport), mux)
+		assert.Nil(t, err)
+	}()
+
+	testClient := client.NewClient("localhost", testport)
+	testClient.Interval = 10 * time.Millisecond
+	testClient.Profiles = []string{client.ProfileBlock}
+	testClient.Metrics = client.MetricsHeap
+	successful := 0
+	nextScrape(testClient, func(s client.Scrape) bool {
+		assert.Empty(t, s.Profiles)
+		assert.Nil(t, s.Metrics)
+		if s.Err == nil {
+			successful++
+		}
+		return successful == 3
+	})
+}
+
+func TestParseProfiles(t *testing.T) {
+	profiles, err := client.ParseProfiles("block, mutex,block,")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{client.ProfileBlock, client.ProfileMutex}, profiles)
+
+	profiles, err = client.ParseProfiles("")
+	assert.Nil(t, err)
+	assert.Empty(t, profiles)
+
+	_, err = client.ParseProfiles("heap")
+	assert.NotNil(t, err)
+}
+
+func TestMetrics(t *testing.T) {
+	const testport = 6069
+
+	go func() {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/debug/pprof/goroutine", func(w http.ResponseWriter, r *http.Request) {})
+		mux.HandleFunc("/debug/pprof/heap", func(w http.ResponseWriter, r *http.Request) {
+			_ = pprof.Lookup("heap").WriteTo(w, 1)
+		})
+		mux.HandleFunc("/debug/pprof/threadcreate", func(w http.ResponseWriter, r *http.Request) {
+			_ = pprof.Lookup("threadcreate").WriteTo(w, 1)
+		})
+		mux.Handle("/debug/vars", expvar.Handler())
+		err := http.ListenAndServe(fmt.Sprintf("localhost:%d", testport), mux)
+		assert.Nil(t, err)
+	}()
+
+	for _, source := range []string{client.MetricsHeap, client.MetricsExpvar} {
+		testClient := client.NewClient("localhost", testport)
+		testClient.Metrics = source
+		testClient.ProfileInterval = 100 * time.Millisecond
+		s := nextScrape(testClient, func(s client.Scrape) bool { return s.Metrics != nil })
+		assert.Nil(t, s.MetricsErr, source)
+		if assert.NotNil(t, s.Metrics, source) {
+			assert.True(t, s.Metrics.HeapInuse > 0, source)
+			assert.True(t, s.Metrics.Threads > 0, source)
+		}
+	}
+
+	testClient := client.NewClient("localhost", testport)
+	testClient.Metrics = "unknown"
+	testClient.ProfileInterval = 100 * time.Millisecond
+	s := nextScrape(testClient, func(s client.Scrape) bool { return s.MetricsErr != nil })
+	assert.NotNil(t, s.MetricsErr)
+	assert.Nil(t, s.Metrics)
+}
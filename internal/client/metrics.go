@@ -0,0 +1,132 @@
+package client
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Sources of runtime metrics
+const (
+	MetricsHeap   = "heap"   // runtime.MemStats trailer of /debug/pprof/heap?debug=1. Contains all heap profile samples
+	MetricsExpvar = "expvar" // memstats of the expvar endpoint /debug/vars. Cheaper but needs the expvar package
+)
+
+// Metrics are runtime metrics of the monitored process
+type Metrics struct {
+	HeapInuse uint64 // Bytes in in-use heap spans
+	NumGC     uint32 // Number of completed GC cycles
+	Threads   int    // Number of created OS threads. Zero if unknown
+}
+
+// get requests the URL and passes the body to parse
+func (client *Client) get(url string, parse func(io.Reader) error) error {
+	resp, err := client.c.Get(url)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Printf("Error while closing response body: %s", err.Error())
+		}
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status: %s", resp.Status)
+	}
+	return parse(resp.Body)
+}
+
+// parseMemStats reads the runtime.MemStats trailer of a heap profile in the text format (debug=1)
+func parseMemStats(reader io.Reader, m *Metrics) error {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(nil, 1024*1024)
+	trailer := false
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "# runtime.MemStats" {
+			trailer = true
+			continue
+		}
+		key, value, found := strings.Cut(strings.TrimPrefix(line, "# "), " = ")
+		if !trailer || !found {
+			continue
+		}
+		var err error
+		switch key {
+		case "HeapInuse":
+			m.HeapInuse, err = strconv.ParseUint(value, 10, 64)
+		case "NumGC":
+			var n uint64
+			n, err = strconv.ParseUint(value, 10, 32)
+			m.NumGC = uint32(n)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid %s. Err: %s", key, err.Error())
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if !trailer {
+		return fmt.Errorf("no runtime.MemStats in heap profile")
+	}
+	return nil
+}
+
+// parseExpvar reads the memstats of the expvar JSON
+func parseExpvar(reader io.Reader, m *Metrics) error {
+	var vars struct {
+		MemStats *struct {
+			HeapInuse uint64
+			NumGC     uint32
+		} `json:"memstats"`
+	}
+	if err := json.NewDecoder(reader).Decode(&vars); err != nil {
+		return err
+	}
+	if vars.MemStats == nil {
+		return fmt.Errorf("no memstats in expvar")
+	}
+	m.HeapInuse = vars.MemStats.HeapInuse
+	m.NumGC = vars.MemStats.NumGC
+	return nil
+}
+
+// parseThreads reads the total of a threadcreate profile in the text format (debug=1) such as threadcreate profile: total 12
+func parseThreads(reader io.Reader, m *Metrics) error {
+	line, err := bufio.NewReader(reader).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return err
+	}
+	if _, err := fmt.Sscanf(line, "threadcreate profile: total %d", &m.Threads); err != nil {
+		return fmt.Errorf("invalid threadcreate profile header %q", strings.TrimSpace(line))
+	}
+	return nil
+}
+
+// scrapeMetrics requests the runtime metrics from the source. The thread count is taken from the threadcreate profile
+func (client *Client) scrapeMetrics() (*Metrics, error) {
+	m := &Metrics{}
+	var err error
+	switch client.Metrics {
+	case MetricsHeap:
+		err = client.get(client.base+"heap?debug=1", func(r io.Reader) error { return parseMemStats(r, m) })
+	case MetricsExpvar:
+		err = client.get(client.vars, func(r io.Reader) error { return parseExpvar(r, m) })
+	default:
+		err = fmt.Errorf("unknown source %q", client.Metrics)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s metrics. Err: %s", client.Metrics, err.Error())
+	}
+	// Thread count is optional
+	if err := client.get(client.base+"threadcreate?debug=1", func(r io.Reader) error { return parseThreads(r, m) }); err != nil {
+		log.Printf("Failed to fetch thread count. Err: %s", err.Error())
+	}
+	return m, nil
+}
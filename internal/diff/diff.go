@@ -0,0 +1,146 @@
+// Package diff compares two goroutine dumps by groups of identical stacks
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/becheran/roumon/internal/model"
+)
+
+// Change of a stack group between two dumps
+type Change string
+
+// Kinds of changes
+const (
+	Appeared    Change = "appeared"
+	Grew        Change = "grew"
+	Shrank      Change = "shrank"
+	Disappeared Change = "disappeared"
+	Unchanged   Change = "unchanged"
+)
+
+// Entry is the difference of one stack group
+type Entry struct {
+	Change     Change
+	Before     int
+	After      int
+	Delta      int
+	Labels     map[string]string
+	StackTrace []model.StackFrame
+}
+
+// Report is the difference of two dumps
+type Report struct {
+	Before    int // Total goroutines of the first dump
+	After     int // Total goroutines of the second dump
+	Unchanged int // Number of stack groups with equal count
+	Entries   []Entry
+}
+
+// key identifies a group by stack and labels across dumps
+func key(g model.Group) string {
+	return model.Goroutine{StackTrace: g.StackTrace}.StackID() + model.FormatLabels(g.Labels)
+}
+
+// Compare returns the stack groups which appeared, grew, shrank or disappeared from before to after.
+// Largest absolute changes first
+func Compare(before, after []model.Group) Report {
+	report := Report{Before: model.Total(before), After: model.Total(after)}
+	counts := make(map[string]*Entry)
+	var keys []string
+	add := func(g model.Group, isAfter bool) {
+		k := key(g)
+		e, ok := counts[k]
+		if !ok {
+			e = &Entry{Labels: g.Labels, StackTrace: g.StackTrace}
+			counts[k] = e
+			keys = append(keys, k)
+		}
+		if isAfter {
+			e.After += g.Count
+		} else {
+			e.Before += g.Count
+		}
+	}
+	for _, g := range before {
+		add(g, false)
+	}
+	for _, g := range after {
+		add(g, true)
+	}
+
+	for _, k := range keys {
+		e := counts[k]
+		e.Delta = e.After - e.Before
+		switch {
+		case e.Delta == 0:
+			report.Unchanged++
+			continue
+		case e.Before == 0:
+			e.Change = Appeared
+		case e.After == 0:
+			e.Change = Disappeared
+		case e.Delta > 0:
+			e.Change = Grew
+		default:
+			e.Change = Shrank
+		}
+		report.Entries = append(report.Entries, *e)
+	}
+	sort.SliceStable(report.Entries, func(i, j int) bool {
+		return abs(report.Entries[i].Delta) > abs(report.Entries[j].Delta)
+	})
+	return report
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// Name returns the short name of the first own frame of the entry or of the top frame
+func (e Entry) Name(c model.Classifier) string {
+	frame, ok := c.NameFrame(e.StackTrace)
+	if !ok {
+		return "(no frames)"
+	}
+	return fmt.Sprintf("%s %s", frame.ShortName(), frame.Location())
+}
+
+// WriteText writes a human readable report. The full stacks are included if stacks is set
+func WriteText(w io.Writer, report Report, c model.Classifier, stacks bool) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Goroutines: %d -> %d (%+d)\n", report.Before, report.After, report.After-report.Before)
+	fmt.Fprintf(&sb, "Changed stacks: %d, unchanged stacks: %d\n", len(report.Entries), report.Unchanged)
+	if len(report.Entries) > 0 {
+		sb.WriteByte('\n')
+	}
+	for _, e := range report.Entries {
+		fmt.Fprintf(&sb, "%-12s %+6d %6d -> %-6d %s", strings.ToUpper(string(e.Change)), e.Delta, e.Before, e.After, e.Name(c))
+		if len(e.Labels) > 0 {
+			fmt.Fprintf(&sb, " %s", model.FormatLabels(e.Labels))
+		}
+		sb.WriteByte('\n')
+		if stacks {
+			for _, f := range e.StackTrace {
+				fmt.Fprintf(&sb, "    %s\n        %s\n", f.FuncName, f.Location())
+			}
+			sb.WriteByte('\n')
+		}
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// WriteJSON writes the report as JSON
+func WriteJSON(w io.Writer, report Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
@@ -0,0 +1,89 @@
+package diff_test
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/becheran/roumon/internal/diff"
+	"github.com/becheran/roumon/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func dump(stacks map[string]int) string {
+	var sb strings.Builder
+	id := 1
+	for _, name := range []string{"main.a", "main.b", "main.c", "main.d"} {
+		for i := 0; i < stacks[name]; i++ {
+			sb.WriteString("goroutine ")
+			sb.WriteString(strconv.Itoa(id))
+			sb.WriteString(" [select]:\n" + name + "()\n\t/app/main.go:10 +0x1d\n\n")
+			id++
+		}
+	}
+	return sb.String()
+}
+
+func TestCompare(t *testing.T) {
+	before, err := model.ParseDump([]byte(dump(map[string]int{"main.a": 2, "main.b": 3, "main.c": 1})))
+	assert.Nil(t, err)
+	after, err := model.ParseDump([]byte(dump(map[string]int{"main.a": 2, "main.b": 1, "main.c": 4, "main.d": 5})))
+	assert.Nil(t, err)
+
+	report := diff.Compare(before, after)
+	assert.Equal(t, 6, report.Before)
+	assert.Equal(t, 12, report.After)
+	assert.Equal(t, 1, report.Unchanged)
+	assert.Len(t, report.Entries, 3)
+
+	assert.Equal(t, diff.Appeared, report.Entries[0].Change)
+	assert.Equal(t, "main.d", report.Entries[0].StackTrace[0].FuncName)
+	assert.Equal(t, 5, report.Entries[0].Delta)
+	assert.Equal(t, diff.Grew, report.Entries[1].Change)
+	assert.Equal(t, 3, report.Entries[1].Delta)
+	assert.Equal(t, diff.Shrank, report.Entries[2].Change)
+	assert.Equal(t, 3, report.Entries[2].Before)
+	assert.Equal(t, 1, report.Entries[2].After)
+
+	report = diff.Compare(after, before)
+	assert.Equal(t, diff.Disappeared, report.Entries[0].Change)
+	assert.Equal(t, -5, report.Entries[0].Delta)
+}
+
+func TestCompareLabels(t *testing.T) {
+	stack := []model.StackFrame{{FuncName: "main.a", File: "/app/main.go", Line: 1}}
+	before := []model.Group{{Count: 2, StackTrace: stack, Labels: map[string]string{"tenant": "a"}}}
+	after := []model.Group{
+		{Count: 2, StackTrace: stack, Labels: map[string]string{"tenant": "a"}},
+		{Count: 1, StackTrace: stack, Labels: map[string]string{"tenant": "b"}},
+	}
+	report := diff.Compare(before, after)
+	assert.Equal(t, 1, report.Unchanged)
+	assert.Len(t, report.Entries, 1)
+	assert.Equal(t, map[string]string{"tenant": "b"}, report.Entries[0].Labels)
+}
+
+func TestWriteReport(t *testing.T) {
+	before, _ := model.ParseDump([]byte(dump(map[string]int{"main.a": 2})))
+	after, _ := model.ParseDump([]byte(dump(map[string]int{"main.a": 1, "main.b": 3})))
+	report := diff.Compare(before, after)
+
+	var sb strings.Builder
+	assert.Nil(t, diff.WriteText(&sb, report, model.NewClassifier(""), false))
+	assert.Equal(t, "Goroutines: 2 -> 4 (+2)\n"+
+		"Changed stacks: 2, unchanged stacks: 0\n\n"+
+		"APPEARED         +3      0 -> 3      main.b /app/main.go:10\n"+
+		"SHRANK           -1      2 -> 1      main.a /app/main.go:10\n", sb.String())
+
+	sb.Reset()
+	assert.Nil(t, diff.WriteText(&sb, report, model.NewClassifier(""), true))
+	assert.Contains(t, sb.String(), "    main.b\n        /app/main.go:10\n")
+
+	sb.Reset()
+	assert.Nil(t, diff.WriteJSON(&sb, report))
+	var decoded diff.Report
+	assert.Nil(t, json.Unmarshal([]byte(sb.String()), &decoded))
+	assert.Equal(t, report.Before, decoded.Before)
+	assert.Equal(t, diff.Appeared, decoded.Entries[0].Change)
+}
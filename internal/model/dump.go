@@ -0,0 +1,32 @@
+package model
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+)
+
+// LoadDump reads a goroutine dump file and groups identical stacks.
+// Supports the pprof debug=2 and debug=1 text formats and the protobuf format
+func LoadDump(path string) ([]Group, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dump %s. Err: %s", path, err.Error())
+	}
+	return ParseDump(data)
+}
+
+// ParseDump detects the format of the goroutine dump and groups identical stacks
+func ParseDump(data []byte) ([]Group, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0x1f, 0x8b}):
+		return ParseProfile(bytes.NewReader(data))
+	case bytes.HasPrefix(bytes.TrimSpace(data), []byte("goroutine profile:")):
+		return ParseGroups(bytes.NewReader(data))
+	}
+	routines, err := ParseStackFrame(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return GroupByStack(routines), nil
+}
@@ -0,0 +1,21 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/becheran/roumon/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDump(t *testing.T) {
+	groups, err := model.ParseDump([]byte("goroutine profile: total 3\n3 @ 0x1\n#\t0x1\tmain.main+0x1\t/app/main.go:1\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, 3, model.Total(groups))
+
+	groups, err = model.ParseDump([]byte(trace_2))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, model.Total(groups))
+
+	_, err = model.LoadDump("does-not-exist.txt")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,154 @@
+package model
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Func is the decomposed function name of a stack frame.
+// For example net/http.(*conn).serve.func1 is split into
+// import path net/http, package http, pointer receiver conn, name serve and closure func1
+type Func struct {
+	ImportPath      string // Full import path such as net/http or main
+	Package         string // Last element of the import path
+	Receiver        string // Receiver type of a method without type parameters. Empty for functions
+	PointerReceiver bool
+	Name            string // Function or method name
+	Closure         string // Closure or wrapper suffix such as func1, func1.2 or gowrap1. Empty if none
+	TypeParams      string // Type parameters of a generic function or receiver such as [...]
+}
+
+// ParseFunc decomposes a function name as printed in goroutine dumps. Arguments must be removed beforehand.
+// Names which do not match the expected form are kept as Name
+func ParseFunc(name string) (f Func) {
+	// The linker escapes dots in the last element of the import path. See: cmd/internal/objabi.PathToPrefix
+	pkgEnd := strings.LastIndex(name, "/") + 1
+	dot := strings.Index(name[pkgEnd:], ".")
+	if dot < 0 {
+		f.Name = name
+		return
+	}
+	f.ImportPath = strings.ReplaceAll(name[:pkgEnd+dot], "%2e", ".")
+	f.Package = f.ImportPath[strings.LastIndex(f.ImportPath, "/")+1:]
+	rest := name[pkgEnd+dot+1:]
+
+	if strings.HasPrefix(rest, "(") {
+		end := strings.Index(rest, ").")
+		if end < 0 {
+			f.Name = rest
+			return
+		}
+		f.Receiver = rest[1:end]
+		if strings.HasPrefix(f.Receiver, "*") {
+			f.PointerReceiver = true
+			f.Receiver = f.Receiver[1:]
+		}
+		f.Receiver, f.TypeParams = cutTypeParams(f.Receiver)
+		rest = rest[end+2:]
+	}
+
+	parts := splitFunc(rest)
+	f.Name = parts[0]
+	parts = parts[1:]
+	// Value receiver methods are printed as Type.Method
+	if f.Receiver == "" && len(parts) > 0 && !isClosure(parts[0]) {
+		f.Receiver, f.TypeParams = cutTypeParams(f.Name)
+		f.Name = parts[0]
+		parts = parts[1:]
+	}
+	if f.TypeParams == "" {
+		f.Name, f.TypeParams = cutTypeParams(f.Name)
+	}
+	f.Closure = strings.Join(parts, ".")
+	return
+}
+
+// Method returns true if the function has a receiver
+func (f Func) Method() bool {
+	return f.Receiver != ""
+}
+
+// splitFunc splits the name at dots which are not part of type parameters. Empty parts such as in glob..func1 are dropped
+func splitFunc(name string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i, c := range name {
+		switch c {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case '.':
+			if depth == 0 {
+				if i > start {
+					parts = append(parts, name[start:i])
+				}
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, name[start:])
+}
+
+// isClosure returns true for the compiler generated names of closures and wrappers
+func isClosure(part string) bool {
+	for _, prefix := range []string{"func", "gowrap", "deferwrap"} {
+		if n, found := strings.CutPrefix(part, prefix); found {
+			_, err := strconv.Atoi(n)
+			return err == nil
+		}
+	}
+	_, err := strconv.Atoi(part)
+	return err == nil
+}
+
+func cutTypeParams(name string) (string, string) {
+	if i := strings.Index(name, "["); i > 0 && strings.HasSuffix(name, "]") {
+		return name[:i], name[i:]
+	}
+	return name, ""
+}
+
+// ParseCall splits a stack frame line such as main.foo(0x1, {0x2, 0x3}) into the function name and the arguments
+func ParseCall(line string) (name string, args []string) {
+	if !strings.HasSuffix(line, ")") {
+		return line, nil
+	}
+	// Find the opening parenthesis of the argument list. Receivers such as (*conn) are not at the end of the line
+	depth := 0
+	open := -1
+	for i := len(line) - 1; i >= 0 && open < 0; i-- {
+		switch line[i] {
+		case ')':
+			depth++
+		case '(':
+			depth--
+			if depth == 0 {
+				open = i
+			}
+		}
+	}
+	if open <= 0 {
+		return line, nil
+	}
+
+	args = []string{}
+	depth, start := 0, open+1
+	for i := open + 1; i < len(line)-1; i++ {
+		switch line[i] {
+		case '{', '(':
+			depth++
+		case '}', ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				args = append(args, strings.TrimSpace(line[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	if last := strings.TrimSpace(line[start : len(line)-1]); last != "" || len(args) > 0 {
+		args = append(args, last)
+	}
+	return line[:open], args
+}
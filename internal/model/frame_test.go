@@ -0,0 +1,181 @@
+package model_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/becheran/roumon/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFunc(t *testing.T) {
+	tests := []struct {
+		name string
+		want model.Func
+	}{
+		{"main.main", model.Func{ImportPath: "main", Package: "main", Name: "main"}},
+		{"net/http.(*conn).serve", model.Func{ImportPath: "net/http", Package: "http", Receiver: "conn", PointerReceiver: true, Name: "serve"}},
+		{"net/http.(*Server).Serve.func3", model.Func{ImportPath: "net/http", Package: "http", Receiver: "Server", PointerReceiver: true, Name: "Serve", Closure: "func3"}},
+		{"time.Time.String", model.Func{ImportPath: "time", Package: "time", Receiver: "Time", Name: "String"}},
+		{"main.foo[...]", model.Func{ImportPath: "main", Package: "main", Name: "foo", TypeParams: "[...]"}},
+		{"main.foo[...].func1", model.Func{ImportPath: "main", Package: "main", Name: "foo", TypeParams: "[...]", Closure: "func1"}},
+		{"main.(*List[...]).Push", model.Func{ImportPath: "main", Package: "main", Receiver: "List", PointerReceiver: true, Name: "Push", TypeParams: "[...]"}},
+		{"main.Set[...].Has", model.Func{ImportPath: "main", Package: "main", Receiver: "Set", Name: "Has", TypeParams: "[...]"}},
+		{"main.main.func1.2", model.Func{ImportPath: "main", Package: "main", Name: "main", Closure: "func1.2"}},
+		{"main.main.gowrap1", model.Func{ImportPath: "main", Package: "main", Name: "main", Closure: "gowrap1"}},
+		{"main.run.deferwrap2", model.Func{ImportPath: "main", Package: "main", Name: "run", Closure: "deferwrap2"}},
+		{"example.com/app.glob..func1", model.Func{ImportPath: "example.com/app", Package: "app", Name: "glob", Closure: "func1"}},
+		{"gopkg.in/yaml%2ev3.(*decoder).unmarshal", model.Func{ImportPath: "gopkg.in/yaml.v3", Package: "yaml.v3", Receiver: "decoder", PointerReceiver: true, Name: "unmarshal"}},
+		{"company/foo/bar/SecureTest/cmd/TestService/foo.Initfoo.func1", model.Func{ImportPath: "company/foo/bar/SecureTest/cmd/TestService/foo", Package: "foo", Name: "Initfoo", Closure: "func1"}},
+		{"nopackage", model.Func{Name: "nopackage"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, model.ParseFunc(tt.name))
+		})
+	}
+	assert.True(t, model.ParseFunc("time.Time.String").Method())
+	assert.False(t, model.ParseFunc("main.main.func1").Method())
+}
+
+func TestParseCall(t *testing.T) {
+	tests := []struct {
+		line string
+		name string
+		args []string
+	}{
+		{"main.main()", "main.main", []string{}},
+		{"net/http.(*conn).serve(0xc000138000, {0xe52ee0, 0xc0000c8660})", "net/http.(*conn).serve", []string{"0xc000138000", "{0xe52ee0, 0xc0000c8660}"}},
+		{"syscall.Syscall9(0x7, 0x1f4, ...)", "syscall.Syscall9", []string{"0x7", "0x1f4", "..."}},
+		{"main.foo[...](0x1?)", "main.foo[...]", []string{"0x1?"}},
+		{"main.(*T).M(...)", "main.(*T).M", []string{"..."}},
+		{"main.main", "main.main", nil},
+		{"main.(*T).M", "main.(*T).M", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			name, args := model.ParseCall(tt.line)
+			assert.Equal(t, tt.name, name)
+			assert.Equal(t, tt.args, args)
+		})
+	}
+}
+
+var trace_createdIn = `goroutine 7 [select]:
+main.(*Worker).run(0xc000010000, {0x1, 0x2})
+	/app/main.go:20 +0x1d
+created by main.main in goroutine 1
+	/app/main.go:10 +0x25`
+
+func TestParseFrameFields(t *testing.T) {
+	routines, err := model.ParseStackFrameStrict(strings.NewReader(trace_createdIn))
+	assert.Nil(t, err)
+	assert.Len(t, routines, 1)
+	r := routines[0]
+	assert.Equal(t, int64(1), r.CreatorID)
+	assert.Equal(t, "main.main", r.CratedBy.FuncName)
+	assert.Equal(t, "main", r.CratedBy.Func.Name)
+	assert.Nil(t, r.CratedBy.Args)
+
+	frame := r.StackTrace[0]
+	assert.Equal(t, "main.(*Worker).run", frame.FuncName)
+	assert.Equal(t, []string{"0xc000010000", "{0x1, 0x2}"}, frame.Args)
+	assert.Equal(t, "Worker", frame.Func.Receiver)
+	assert.True(t, frame.Func.PointerReceiver)
+	assert.Equal(t, "run", frame.Func.Name)
+	assert.Equal(t, "main.(*Worker).run(0xc000010000, {0x1, 0x2})", frame.Call())
+
+	assert.Equal(t, trace_createdIn+"\n", r.Text())
+}
+
+func TestParseStackPosVariants(t *testing.T) {
+	pos := func(p int) *int { return &p }
+	tests := []struct {
+		text string
+		file string
+		line int32
+		pos  *int
+	}{
+		{"/usr/local/go/src/net/http/server.go:2969 +0x970", "/usr/local/go/src/net/http/server.go", 2969, pos(0x970)},
+		{"\t/usr/local/go/src/net/http/server.go:2969 +0x970", "/usr/local/go/src/net/http/server.go", 2969, pos(0x970)},
+		{"/app/main.go:10 +0x0", "/app/main.go", 10, pos(0)},
+		{"/app/main.go:10", "/app/main.go", 10, nil},
+		{"C:/Program Files/Go/src/runtime/syscall_windows.go:356 +0xf2", "C:/Program Files/Go/src/runtime/syscall_windows.go", 356, pos(0xf2)},
+		{"C:/Program Files/Go/src/runtime/syscall_windows.go:356", "C:/Program Files/Go/src/runtime/syscall_windows.go", 356, nil},
+		{"_testmain.go:44 +0x1a", "_testmain.go", 44, pos(0x1a)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			file, line, p, err := model.ParseStackPos(tt.text)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.file, file)
+			assert.Equal(t, tt.line, line)
+			assert.Equal(t, tt.pos, p)
+
+			frame := model.StackFrame{FuncName: "main.f", File: file, Line: line, Position: p}
+			id := frame.ID()
+			assert.Equal(t, tt.pos != nil, id.HasOffset)
+			if tt.pos != nil {
+				assert.Equal(t, *tt.pos, id.Offset)
+				assert.Equal(t, fmt.Sprintf("main.f\n   file://%s#%d +0x%x", tt.file, tt.line, *tt.pos), frame.String())
+				assert.Equal(t, fmt.Sprintf("main.f %s:%d +0x%x", tt.file, tt.line, *tt.pos), id.String())
+			} else {
+				assert.Equal(t, fmt.Sprintf("main.f\n   file://%s#%d", tt.file, tt.line), frame.String())
+				assert.Equal(t, fmt.Sprintf("main.f %s:%d", tt.file, tt.line), id.String())
+			}
+		})
+	}
+}
+
+func TestFrameID(t *testing.T) {
+	zero := 0
+	withOffset := model.StackFrame{FuncName: "main.f", File: "/app/main.go", Line: 10, Position: &zero, Args: []string{"0x1"}}
+	withoutOffset := model.StackFrame{FuncName: "main.f", File: "/app/main.go", Line: 10}
+	assert.NotEqual(t, withOffset.ID(), withoutOffset.ID())
+
+	other := 0
+	sameFrame := model.StackFrame{FuncName: "main.f", File: "/app/main.go", Line: 10, Position: &other, Args: []string{"0x2"}}
+	assert.Equal(t, withOffset.ID(), sameFrame.ID())
+
+	ids := map[model.FrameID]int{withOffset.ID(): 1}
+	ids[sameFrame.ID()]++
+	ids[withoutOffset.ID()]++
+	assert.Equal(t, 2, ids[withOffset.ID()])
+	assert.Equal(t, 1, ids[withoutOffset.ID()])
+}
+
+func TestStackID(t *testing.T) {
+	routines, err := model.ParseStackFrameStrict(strings.NewReader(trace_createdIn + "\n\n" + strings.Replace(trace_createdIn, "goroutine 7", "goroutine 8", 1)))
+	assert.Nil(t, err)
+	assert.Len(t, routines, 2)
+	assert.Equal(t, routines[0].StackID(), routines[1].StackID())
+	assert.Equal(t, "main.(*Worker).run /app/main.go:20 +0x1d\ncreated by main.main /app/main.go:10 +0x25", routines[0].StackID())
+
+	routines[1].StackTrace[0].Position = nil
+	assert.NotEqual(t, routines[0].StackID(), routines[1].StackID())
+}
+
+func TestFingerprint(t *testing.T) {
+	pos := 0x1d
+	a := []model.StackFrame{{FuncName: "main.worker", File: "/app/main.go", Line: 20, Position: &pos}, {FuncName: "main.main", File: "/app/main.go", Line: 10}}
+	b := []model.StackFrame{{FuncName: "main.worker", File: "/build/main.go", Line: 22}, {FuncName: "main.main", File: "/build/main.go", Line: 11}}
+	assert.Equal(t, model.Fingerprint(a), model.Fingerprint(b))
+	assert.Len(t, model.Fingerprint(a), 16)
+	assert.NotEqual(t, model.Fingerprint(a), model.Fingerprint(a[:1]))
+	assert.NotEqual(t, model.Fingerprint(a), model.Fingerprint([]model.StackFrame{a[1], a[0]}))
+
+	// The debug=1 format shows runtime frames which are hidden by the debug=2 format
+	gopark := model.StackFrame{FuncName: "runtime.gopark", Func: model.ParseFunc("runtime.gopark")}
+	runtimeMain := model.StackFrame{FuncName: "runtime.main", Func: model.ParseFunc("runtime.main")}
+	assert.Equal(t, model.Fingerprint(a), model.Fingerprint([]model.StackFrame{gopark, a[0], a[1], runtimeMain}))
+
+	// Runtime frames between other frames are part of the stack
+	assert.NotEqual(t, model.Fingerprint(a), model.Fingerprint([]model.StackFrame{a[0], gopark, a[1]}))
+
+	// Stacks of runtime frames only such as the finalizer and GC workers are distinct
+	runfinq := model.StackFrame{FuncName: "runtime.runfinq", Func: model.ParseFunc("runtime.runfinq")}
+	bgsweep := model.StackFrame{FuncName: "runtime.bgsweep", Func: model.ParseFunc("runtime.bgsweep")}
+	assert.NotEqual(t, model.Fingerprint([]model.StackFrame{gopark, runfinq}), model.Fingerprint([]model.StackFrame{gopark, bgsweep}))
+	assert.NotEqual(t, model.Fingerprint(nil), model.Fingerprint([]model.StackFrame{gopark, bgsweep}))
+}
@@ -0,0 +1,345 @@
+package model
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Group of goroutines with identical stacks. Either parsed from the pprof debug=1 or protobuf profile formats or grouped from single goroutines.
+// See: https://github.com/DataDog/go-profiler-notes/blob/main/goroutine.md#feature-matrix
+type Group struct {
+	Count      int
+	PCs        []uint64          // Program counters of the stack. Only set by profile formats
+	Labels     map[string]string // pprof labels. Only set by profile formats
+	StackTrace []StackFrame
+	Routines   []Goroutine // Goroutines of the group. Empty for profile formats
+}
+
+// GroupByStack groups the goroutines by identical stacks. Largest groups first
+func GroupByStack(routines []Goroutine) []Group {
+	var groups []Group
+	index := make(map[string]int)
+	for _, r := range routines {
+		id := r.StackID()
+		i, ok := index[id]
+		if !ok {
+			i = len(groups)
+			index[id] = i
+			groups = append(groups, Group{StackTrace: r.StackTrace})
+		}
+		groups[i].Count++
+		groups[i].Routines = append(groups[i].Routines, r)
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].Count > groups[j].Count
+	})
+	return groups
+}
+
+// Total returns the number of goroutines of all groups
+func Total(groups []Group) int {
+	total := 0
+	for _, g := range groups {
+		total += g.Count
+	}
+	return total
+}
+
+// Text returns the group in the pprof debug=1 format
+func (g Group) Text() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d @", g.Count)
+	for _, pc := range g.PCs {
+		fmt.Fprintf(&sb, " %#x", pc)
+	}
+	sb.WriteByte('\n')
+	if len(g.Labels) > 0 {
+		sb.WriteString("# labels: ")
+		sb.WriteString(FormatLabels(g.Labels))
+		sb.WriteByte('\n')
+	}
+	for _, s := range g.StackTrace {
+		fmt.Fprintf(&sb, "#\t%#x\t%s", s.PC, s.FuncName)
+		if s.Position != nil {
+			fmt.Fprintf(&sb, "+%#x", *s.Position)
+		}
+		fmt.Fprintf(&sb, "\t%s\n", s.Location())
+	}
+	return sb.String()
+}
+
+// FormatLabels returns the labels as printed by pprof such as {"key":"value", "other":"value"}. Keys are sorted
+func FormatLabels(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = fmt.Sprintf("%q:%q", k, labels[k])
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+// ParseLabels parses labels of the form {"key":"value", "other":"value"}
+func ParseLabels(text string) (map[string]string, error) {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "{") || !strings.HasSuffix(text, "}") {
+		return nil, fmt.Errorf("expected labels of form {\"key\":\"value\"}, but got: %s", text)
+	}
+	rest := strings.TrimSpace(text[1 : len(text)-1])
+	labels := make(map[string]string)
+	unquote := func() (string, error) {
+		quoted, err := strconv.QuotedPrefix(rest)
+		if err != nil {
+			return "", fmt.Errorf("expected quoted string in labels %s. Err: %s", text, err.Error())
+		}
+		rest = rest[len(quoted):]
+		return strconv.Unquote(quoted)
+	}
+	for rest != "" {
+		key, err := unquote()
+		if err != nil {
+			return nil, err
+		}
+		var found bool
+		if rest, found = strings.CutPrefix(rest, ":"); !found {
+			return nil, fmt.Errorf("expected : after label key %s in %s", key, text)
+		}
+		value, err := unquote()
+		if err != nil {
+			return nil, err
+		}
+		labels[key] = value
+		rest = strings.TrimSpace(strings.TrimPrefix(rest, ","))
+	}
+	return labels, nil
+}
+
+// WriteGroups writes all groups in the pprof debug=1 format which can be read by ParseGroups
+func WriteGroups(writer io.Writer, groups []Group) error {
+	if _, err := fmt.Fprintf(writer, "goroutine profile: total %d\n", Total(groups)); err != nil {
+		return err
+	}
+	for _, g := range groups {
+		if _, err := io.WriteString(writer, g.Text()+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseGroupHeader parses the header of a group such as 3 @ 0x43a5d6 0x4095fd
+func parseGroupHeader(header string) (group Group, err error) {
+	count, pcs, found := strings.Cut(header, " @")
+	if !found {
+		err = fmt.Errorf("expected group header of form \"<count> @ <pcs>\", but got: %s", header)
+		return
+	}
+	group.Count, err = strconv.Atoi(count)
+	if err != nil {
+		err = fmt.Errorf("could not parse count. Err: %s", err.Error())
+		return
+	}
+	for _, pc := range strings.Fields(pcs) {
+		v, parseErr := strconv.ParseUint(pc, 0, 64)
+		if parseErr != nil {
+			err = fmt.Errorf("could not parse program counter %s. Err: %s", pc, parseErr.Error())
+			return
+		}
+		group.PCs = append(group.PCs, v)
+	}
+	return
+}
+
+// parseGroupFrame parses one frame of a group such as #	0x67e51d	main.main+0x3d	/tmp/main.go:32
+func parseGroupFrame(text string) (frame StackFrame, err error) {
+	fields := strings.FieldsFunc(strings.TrimPrefix(text, "#"), func(r rune) bool { return r == '\t' })
+	if len(fields) != 3 {
+		err = fmt.Errorf("expected frame of form \"#<tab><pc><tab><func>+<offset><tab><file>:<line>\", but got: %s", text)
+		return
+	}
+	frame.PC, err = strconv.ParseUint(strings.TrimSpace(fields[0]), 0, 64)
+	if err != nil {
+		err = fmt.Errorf("could not parse program counter. Err: %s", err.Error())
+		return
+	}
+	frame.FuncName = strings.TrimSpace(fields[1])
+	if i := strings.LastIndex(frame.FuncName, "+0x"); i > 0 {
+		offset, parseErr := strconv.ParseInt(frame.FuncName[i+3:], 16, 64)
+		if parseErr != nil {
+			err = fmt.Errorf("could not parse offset of %s. Err: %s", frame.FuncName, parseErr.Error())
+			return
+		}
+		pos := int(offset)
+		frame.Position = &pos
+		frame.FuncName = frame.FuncName[:i]
+	}
+	frame.Func = ParseFunc(frame.FuncName)
+	frame.File, frame.Line, _, err = ParseStackPos(fields[2])
+	return
+}
+
+// ParseGroups reads a goroutine profile in the pprof debug=1 format and returns all groups.
+// Parts which could not be parsed are logged and skipped
+func ParseGroups(reader io.Reader) (groups []Group, err error) {
+	groups, skipped, err := ParseGroupsErrors(reader)
+	for _, e := range skipped {
+		log.Print(e.Error())
+	}
+	return
+}
+
+// ParseGroupsStrict reads a goroutine profile in the pprof debug=1 format and returns all groups.
+// Fails with a *ParseError on the first part which could not be parsed
+func ParseGroupsStrict(reader io.Reader) (groups []Group, err error) {
+	return parseGroups(reader, true, nil)
+}
+
+// ParseGroupsErrors reads a goroutine profile in the pprof debug=1 format and returns all groups.
+// Parts which could not be parsed are skipped and returned as errors
+func ParseGroupsErrors(reader io.Reader) (groups []Group, skipped []*ParseError, err error) {
+	groups, err = parseGroups(reader, false, func(e *ParseError) {
+		skipped = append(skipped, e)
+	})
+	return
+}
+
+func parseGroups(reader io.Reader, strict bool, onError func(*ParseError)) (groups []Group, err error) {
+	scanner := bufio.NewScanner(reader)
+	lineNumber := 0
+	var group *Group
+	// Frames and labels of a group with an invalid header are skipped without reporting each line
+	skipping := false
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Text()
+
+		var kind ParseErrorKind
+		var lineErr error
+		switch {
+		case len(line) == 0:
+			group = nil
+			skipping = false
+			continue
+		case skipping && strings.HasPrefix(line, "#"):
+			continue
+		case strings.HasPrefix(line, "goroutine profile:"):
+			continue
+		case group == nil:
+			g, err := parseGroupHeader(line)
+			if err != nil {
+				kind, lineErr = HeaderError, err
+				skipping = true
+				break
+			}
+			skipping = false
+			groups = append(groups, g)
+			group = &groups[len(groups)-1]
+		case strings.HasPrefix(line, "# labels: "):
+			labels, err := ParseLabels(line[10:])
+			if err != nil {
+				kind, lineErr = LabelsError, err
+				break
+			}
+			group.Labels = labels
+		default:
+			frame, err := parseGroupFrame(line)
+			if err != nil {
+				kind, lineErr = FrameError, err
+				break
+			}
+			group.StackTrace = append(group.StackTrace, frame)
+		}
+
+		if lineErr != nil {
+			parseErr := &ParseError{Kind: kind, Line: lineNumber, Text: line, Err: lineErr}
+			if strict {
+				return nil, parseErr
+			}
+			onError(parseErr)
+		}
+	}
+
+	err = scanner.Err()
+	return
+}
+
+// LabelCount is the number of goroutines with one value of a label key
+type LabelCount struct {
+	Value string
+	Count int
+}
+
+// LabelCounts returns the number of goroutines per label key and value. Values are ordered by count descending
+func LabelCounts(groups []Group) map[string][]LabelCount {
+	counts := make(map[string]map[string]int)
+	for _, g := range groups {
+		for k, v := range g.Labels {
+			if counts[k] == nil {
+				counts[k] = make(map[string]int)
+			}
+			counts[k][v] += g.Count
+		}
+	}
+	result := make(map[string][]LabelCount, len(counts))
+	for k, values := range counts {
+		for v, n := range values {
+			result[k] = append(result[k], LabelCount{Value: v, Count: n})
+		}
+		slices.SortFunc(result[k], func(a, b LabelCount) int {
+			if a.Count != b.Count {
+				return b.Count - a.Count
+			}
+			return strings.Compare(a.Value, b.Value)
+		})
+	}
+	return result
+}
+
+// LabelFilter matches groups by a label key and optionally a value
+type LabelFilter struct {
+	Key      string
+	Value    string
+	AnyValue bool // Match all groups which have the key
+}
+
+// ParseLabelFilter parses a filter of the form label:key=value or label:key.
+// Returns false if the text is no label filter
+func ParseLabelFilter(text string) (LabelFilter, bool) {
+	rest, found := strings.CutPrefix(strings.TrimSpace(text), "label:")
+	if !found {
+		return LabelFilter{}, false
+	}
+	key, value, hasValue := strings.Cut(rest, "=")
+	return LabelFilter{Key: key, Value: value, AnyValue: !hasValue}, true
+}
+
+// Match returns true if the labels contain the key and value of the filter
+func (f LabelFilter) Match(labels map[string]string) bool {
+	v, ok := labels[f.Key]
+	return ok && (f.AnyValue || v == f.Value)
+}
+
+func (f LabelFilter) String() string {
+	if f.AnyValue {
+		return "label:" + f.Key
+	}
+	return "label:" + f.Key + "=" + f.Value
+}
+
+// Stacks returns one goroutine without ID and status for each group
+func Stacks(groups []Group) []Goroutine {
+	routines := make([]Goroutine, len(groups))
+	for i, g := range groups {
+		routines[i] = Goroutine{StackTrace: g.StackTrace}
+	}
+	return routines
+}
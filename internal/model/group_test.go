@@ -0,0 +1,145 @@
+package model_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/becheran/roumon/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+var profile_1 = `goroutine profile: total 4
+3 @ 0x48c12a 0x48fec5 0x67e678 0x492fc1
+# labels: {"handler":"/api", "worker":"a \"b\""}
+#	0x48fec4	time.Sleep+0x124		/usr/local/go/src/runtime/time.go:338
+#	0x67e677	main.main.func2.1+0x37		/root/module/test/Server/test.go:24
+
+1 @ 0x48c12a 0x419bae 0x67e51e 0x492fc1
+#	0x67e51d	main.main+0x3d		/root/module/test/Server/test.go:32
+#	0x453006	runtime.main+0x426	/usr/local/go/src/runtime/proc.go:302
+`
+
+func TestParseGroups(t *testing.T) {
+	groups, err := model.ParseGroupsStrict(strings.NewReader(profile_1))
+	assert.Nil(t, err)
+	assert.Len(t, groups, 2)
+	assert.Equal(t, 4, model.Total(groups))
+
+	g := groups[0]
+	assert.Equal(t, 3, g.Count)
+	assert.Equal(t, []uint64{0x48c12a, 0x48fec5, 0x67e678, 0x492fc1}, g.PCs)
+	assert.Equal(t, map[string]string{"handler": "/api", "worker": "a \"b\""}, g.Labels)
+	assert.Len(t, g.StackTrace, 2)
+	frame := g.StackTrace[1]
+	assert.Equal(t, uint64(0x67e677), frame.PC)
+	assert.Equal(t, "main.main.func2.1", frame.FuncName)
+	assert.Equal(t, "func2.1", frame.Func.Closure)
+	assert.Equal(t, 0x37, *frame.Position)
+	assert.Equal(t, "/root/module/test/Server/test.go", frame.File)
+	assert.Equal(t, int32(24), frame.Line)
+
+	assert.Nil(t, groups[1].Labels)
+	assert.Equal(t, "runtime.main", groups[1].StackTrace[1].FuncName)
+}
+
+func TestWriteGroups(t *testing.T) {
+	groups, err := model.ParseGroupsStrict(strings.NewReader(profile_1))
+	assert.Nil(t, err)
+	var sb strings.Builder
+	assert.Nil(t, model.WriteGroups(&sb, groups))
+	reparsed, err := model.ParseGroupsStrict(strings.NewReader(sb.String()))
+	assert.Nil(t, err)
+	assert.Equal(t, groups, reparsed)
+	assert.True(t, strings.HasPrefix(sb.String(), "goroutine profile: total 4\n3 @ 0x48c12a 0x48fec5 0x67e678 0x492fc1\n# labels: {\"handler\":\"/api\", \"worker\":\"a \\\"b\\\"\"}\n#\t0x48fec4\ttime.Sleep+0x124\t/usr/local/go/src/runtime/time.go:338\n"))
+}
+
+func TestParseGroupsErrors(t *testing.T) {
+	invalid := strings.Replace(profile_1, "# labels: {\"handler\"", "# labels: {handler", 1)
+	invalid = strings.Replace(invalid, "test.go:32", "test.go:x", 1)
+	groups, skipped, err := model.ParseGroupsErrors(strings.NewReader(invalid))
+	assert.Nil(t, err)
+	assert.Len(t, groups, 2)
+	assert.Len(t, skipped, 2)
+	assert.Equal(t, model.LabelsError, skipped[0].Kind)
+	assert.Equal(t, 3, skipped[0].Line)
+	assert.Equal(t, model.FrameError, skipped[1].Kind)
+	assert.Equal(t, 8, skipped[1].Line)
+	assert.Len(t, groups[1].StackTrace, 1)
+
+	_, err = model.ParseGroupsStrict(strings.NewReader(invalid))
+	assert.NotNil(t, err)
+
+	_, skipped, err = model.ParseGroupsErrors(strings.NewReader("x @ 0x1\n#\t0x1\tmain.main+0x1\t/tmp/main.go:10\n"))
+	assert.Nil(t, err)
+	assert.Len(t, skipped, 1)
+	assert.Equal(t, model.HeaderError, skipped[0].Kind)
+}
+
+func TestParseLabels(t *testing.T) {
+	labels, err := model.ParseLabels(`{}`)
+	assert.Nil(t, err)
+	assert.Empty(t, labels)
+	labels, err = model.ParseLabels(`{"a":"1", "b":"x, y"}`)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"a": "1", "b": "x, y"}, labels)
+	assert.Equal(t, `{"a":"1", "b":"x, y"}`, model.FormatLabels(labels))
+
+	_, err = model.ParseLabels(`{"a"}`)
+	assert.NotNil(t, err)
+	_, err = model.ParseLabels(`"a":"b"`)
+	assert.NotNil(t, err)
+}
+
+func TestGroupByStack(t *testing.T) {
+	routines, err := model.ParseStackFrameStrict(strings.NewReader(trace_1))
+	assert.Nil(t, err)
+	duplicate := routines[1]
+	duplicate.ID = 99
+	routines = append(routines, duplicate)
+
+	groups := model.GroupByStack(routines)
+	assert.Len(t, groups, 4)
+	assert.Equal(t, 5, model.Total(groups))
+	assert.Equal(t, 2, groups[0].Count)
+	assert.Equal(t, []int64{1, 99}, []int64{groups[0].Routines[0].ID, groups[0].Routines[1].ID})
+	assert.Equal(t, routines[0].StackTrace, groups[1].StackTrace)
+}
+
+func TestLabelCounts(t *testing.T) {
+	groups := []model.Group{
+		{Count: 2, Labels: map[string]string{"tenant": "a", "route": "/x"}},
+		{Count: 5, Labels: map[string]string{"tenant": "b"}},
+		{Count: 1, Labels: map[string]string{"tenant": "a"}},
+		{Count: 7},
+	}
+	counts := model.LabelCounts(groups)
+	assert.Equal(t, map[string][]model.LabelCount{
+		"tenant": {{Value: "b", Count: 5}, {Value: "a", Count: 3}},
+		"route":  {{Value: "/x", Count: 2}},
+	}, counts)
+}
+
+func TestLabelFilter(t *testing.T) {
+	_, ok := model.ParseLabelFilter("tenant=a")
+	assert.False(t, ok)
+
+	f, ok := model.ParseLabelFilter("label:tenant=a")
+	assert.True(t, ok)
+	assert.Equal(t, model.LabelFilter{Key: "tenant", Value: "a"}, f)
+	assert.Equal(t, "label:tenant=a", f.String())
+	assert.True(t, f.Match(map[string]string{"tenant": "a", "route": "/x"}))
+	assert.False(t, f.Match(map[string]string{"tenant": "b"}))
+	assert.False(t, f.Match(nil))
+
+	f, ok = model.ParseLabelFilter("label:tenant")
+	assert.True(t, ok)
+	assert.True(t, f.AnyValue)
+	assert.Equal(t, "label:tenant", f.String())
+	assert.True(t, f.Match(map[string]string{"tenant": "b"}))
+	assert.False(t, f.Match(map[string]string{"route": "/x"}))
+
+	f, ok = model.ParseLabelFilter("label:tenant=")
+	assert.True(t, ok)
+	assert.True(t, f.Match(map[string]string{"tenant": ""}))
+	assert.False(t, f.Match(map[string]string{"tenant": "a"}))
+}
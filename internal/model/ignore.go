@@ -0,0 +1,174 @@
+package model
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// IgnoreKind selects which frames of a goroutine an IgnoreRule is matched against
+type IgnoreKind string
+
+// Kinds of ignore rules
+const (
+	IgnoreTop     IgnoreKind = "top"     // Top frame. Frames of the runtime package are skipped
+	IgnoreAny     IgnoreKind = "any"     // Any frame of the stack
+	IgnoreCreator IgnoreKind = "creator" // Function which created the goroutine. Only known for the debug=2 format
+)
+
+// IgnoreRule hides goroutines whose function matches the pattern.
+// The pattern is a full function name such as net/http.(*Server).Serve or a prefix followed by *
+type IgnoreRule struct {
+	Kind    IgnoreKind
+	Pattern string
+}
+
+// DefaultIgnoreRules hide the goroutine which writes the goroutine profile for the scrape of roumon
+var DefaultIgnoreRules = IgnoreRules{
+	{Kind: IgnoreTop, Pattern: "runtime/pprof.writeGoroutineStacks"}, // debug=2
+	{Kind: IgnoreTop, Pattern: "runtime/pprof.writeRuntimeProfile"},  // debug=1 and debug=0
+}
+
+// ParseIgnoreRule parses a rule of the form top=<func>, any=<func> or creator=<func>
+func ParseIgnoreRule(text string) (IgnoreRule, error) {
+	kind, pattern, found := strings.Cut(strings.TrimSpace(text), "=")
+	rule := IgnoreRule{Kind: IgnoreKind(kind), Pattern: strings.TrimSpace(pattern)}
+	if !found || rule.Pattern == "" {
+		return rule, fmt.Errorf("expected ignore rule of form <kind>=<func>, but got: %s", text)
+	}
+	if rule.Kind != IgnoreTop && rule.Kind != IgnoreAny && rule.Kind != IgnoreCreator {
+		return rule, fmt.Errorf("invalid ignore rule kind %q. Must be one of %q, %q or %q", kind, IgnoreTop, IgnoreAny, IgnoreCreator)
+	}
+	return rule, nil
+}
+
+func (r IgnoreRule) String() string {
+	return string(r.Kind) + "=" + r.Pattern
+}
+
+// matchFunc returns true if the function name matches the pattern of the rule
+func (r IgnoreRule) matchFunc(name string) bool {
+	if prefix, found := strings.CutSuffix(r.Pattern, "*"); found {
+		return strings.HasPrefix(name, prefix)
+	}
+	return name == r.Pattern
+}
+
+// topFrame returns the first frame which is not part of the runtime package. The first frame if there is none
+func topFrame(stack []StackFrame) (StackFrame, bool) {
+	for _, s := range stack {
+		if s.Func.ImportPath != "runtime" {
+			return s, true
+		}
+	}
+	if len(stack) == 0 {
+		return StackFrame{}, false
+	}
+	return stack[0], true
+}
+
+// Match returns true if the goroutine with the stack and creator is ignored by the rule. Creator may be nil
+func (r IgnoreRule) Match(stack []StackFrame, creator *StackFrame) bool {
+	switch r.Kind {
+	case IgnoreTop:
+		top, ok := topFrame(stack)
+		return ok && r.matchFunc(top.FuncName)
+	case IgnoreAny:
+		for _, s := range stack {
+			if r.matchFunc(s.FuncName) {
+				return true
+			}
+		}
+	case IgnoreCreator:
+		return creator != nil && r.matchFunc(creator.FuncName)
+	}
+	return false
+}
+
+// IgnoreRules hide all goroutines which match any of the rules
+type IgnoreRules []IgnoreRule
+
+// LoadIgnoreFile reads ignore rules with one rule per line. Empty lines and lines starting with # are skipped
+func LoadIgnoreFile(path string) (IgnoreRules, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ignore file %s. Err: %s", path, err.Error())
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			log.Printf("Error while closing ignore file %s: %s", path, err.Error())
+		}
+	}()
+
+	var rules IgnoreRules
+	scanner := bufio.NewScanner(f)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule, err := ParseIgnoreRule(line)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ignore file %s in line %d. Err: %s", path, lineNumber, err.Error())
+		}
+		rules = append(rules, rule)
+	}
+	return rules, scanner.Err()
+}
+
+// Match returns true if any rule matches the goroutine with the stack and creator. Creator may be nil
+func (rules IgnoreRules) Match(stack []StackFrame, creator *StackFrame) bool {
+	for _, r := range rules {
+		if r.Match(stack, creator) {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterRoutines returns the goroutines which are not ignored and the number of ignored goroutines
+func (rules IgnoreRules) FilterRoutines(routines []Goroutine) (kept []Goroutine, ignored int) {
+	if len(rules) == 0 {
+		return routines, 0
+	}
+	kept = make([]Goroutine, 0, len(routines))
+	for _, r := range routines {
+		if rules.Match(r.StackTrace, r.CratedBy) {
+			ignored++
+			continue
+		}
+		kept = append(kept, r)
+	}
+	return kept, ignored
+}
+
+// FilterGroups returns the groups which are not ignored and the number of goroutines in ignored groups.
+// Groups have no creator, so creator rules only apply to the goroutines of a group
+func (rules IgnoreRules) FilterGroups(groups []Group) (kept []Group, ignored int) {
+	if len(rules) == 0 || groups == nil {
+		return groups, 0
+	}
+	kept = make([]Group, 0, len(groups))
+	for _, g := range groups {
+		if len(g.Routines) > 0 {
+			routines, n := rules.FilterRoutines(g.Routines)
+			ignored += n
+			if len(routines) > 0 {
+				g.Routines = routines
+				g.Count = len(routines)
+				kept = append(kept, g)
+			}
+			continue
+		}
+		if rules.Match(g.StackTrace, nil) {
+			ignored += g.Count
+			continue
+		}
+		kept = append(kept, g)
+	}
+	return kept, ignored
+}
@@ -0,0 +1,99 @@
+package model_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/becheran/roumon/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+var trace_ignore = `goroutine 20 [running]:
+runtime/pprof.writeGoroutineStacks({0x9f8238, 0xc0000a4000})
+	/usr/local/go/src/runtime/pprof/pprof.go:816 +0x69
+runtime/pprof.writeGoroutine({0x9f8238?, 0xc0000a4000?}, 0x2?)
+	/usr/local/go/src/runtime/pprof/pprof.go:779 +0x25
+created by net/http.(*Server).Serve in goroutine 7
+	/usr/local/go/src/net/http/server.go:3581 +0x4fd
+
+goroutine 7 [IO wait]:
+internal/poll.runtime_pollWait(0x7f19e0691400, 0x72)
+	/usr/local/go/src/runtime/netpoll.go:351 +0x85
+net/http.(*Server).Serve(0xc000100000, {0x9fa5d0, 0xc000012345})
+	/usr/local/go/src/net/http/server.go:3330 +0x30c
+main.main.func1()
+	/app/main.go:20 +0x1d
+created by main.main in goroutine 1
+	/app/main.go:18 +0x25
+
+goroutine 8 [select]:
+main.worker()
+	/app/worker.go:10 +0x1d
+created by os/signal.Notify.func1 in goroutine 1
+	/usr/local/go/src/os/signal/signal.go:151 +0x1f`
+
+func TestParseIgnoreRule(t *testing.T) {
+	rule, err := model.ParseIgnoreRule(" any=database/sql.(*DB).connectionOpener ")
+	assert.Nil(t, err)
+	assert.Equal(t, model.IgnoreRule{Kind: model.IgnoreAny, Pattern: "database/sql.(*DB).connectionOpener"}, rule)
+	assert.Equal(t, "any=database/sql.(*DB).connectionOpener", rule.String())
+
+	for _, invalid := range []string{"", "top", "top=", "bottom=main.main"} {
+		_, err := model.ParseIgnoreRule(invalid)
+		assert.NotNil(t, err, invalid)
+	}
+}
+
+func TestIgnoreRules(t *testing.T) {
+	routines, err := model.ParseStackFrameStrict(strings.NewReader(trace_ignore))
+	assert.Nil(t, err)
+	assert.Len(t, routines, 3)
+
+	ids := func(rules model.IgnoreRules) []int64 {
+		kept, ignored := rules.FilterRoutines(routines)
+		assert.Equal(t, len(routines), len(kept)+ignored)
+		result := []int64{}
+		for _, r := range kept {
+			result = append(result, r.ID)
+		}
+		return result
+	}
+	assert.Equal(t, []int64{20, 7, 8}, ids(nil))
+	assert.Equal(t, []int64{7, 8}, ids(model.DefaultIgnoreRules))
+	// Runtime frames are skipped for the top frame
+	assert.Equal(t, []int64{20, 8}, ids(model.IgnoreRules{{Kind: model.IgnoreTop, Pattern: "internal/poll.*"}}))
+	assert.Equal(t, []int64{20, 7, 8}, ids(model.IgnoreRules{{Kind: model.IgnoreTop, Pattern: "net/http.(*Server).Serve"}}))
+	assert.Equal(t, []int64{20, 8}, ids(model.IgnoreRules{{Kind: model.IgnoreAny, Pattern: "net/http.(*Server).Serve"}}))
+	assert.Equal(t, []int64{7}, ids(model.IgnoreRules{{Kind: model.IgnoreCreator, Pattern: "os/signal.*"}, {Kind: model.IgnoreCreator, Pattern: "net/http.(*Server).Serve"}}))
+
+	groups := model.GroupByStack(append(routines, routines[0]))
+	kept, ignored := model.DefaultIgnoreRules.FilterGroups(groups)
+	assert.Equal(t, 2, ignored)
+	assert.Len(t, kept, 2)
+	assert.Equal(t, 2, model.Total(kept))
+
+	// Profile groups have no creator and no single goroutines
+	for i := range groups {
+		groups[i].Routines = nil
+	}
+	kept, ignored = model.IgnoreRules{{Kind: model.IgnoreCreator, Pattern: "*"}, {Kind: model.IgnoreAny, Pattern: "main.worker"}}.FilterGroups(groups)
+	assert.Equal(t, 1, ignored)
+	assert.Len(t, kept, 2)
+	kept, ignored = model.DefaultIgnoreRules.FilterGroups([]model.Group{})
+	assert.NotNil(t, kept)
+	assert.Equal(t, 0, ignored)
+}
+
+func TestLoadIgnoreFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ignore.txt")
+	assert.Nil(t, os.WriteFile(path, []byte("# Background goroutines\n\ntop=net/http.(*Server).Serve\ncreator=os/signal.*\n"), 0644))
+	rules, err := model.LoadIgnoreFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, model.IgnoreRules{{Kind: model.IgnoreTop, Pattern: "net/http.(*Server).Serve"}, {Kind: model.IgnoreCreator, Pattern: "os/signal.*"}}, rules)
+
+	assert.Nil(t, os.WriteFile(path, []byte("top=main.main\nignore=main.main\n"), 0644))
+	_, err = model.LoadIgnoreFile(path)
+	assert.ErrorContains(t, err, "line 2")
+}
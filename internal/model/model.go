@@ -3,10 +3,12 @@ package model
 import (
 	"bufio"
 	"fmt"
+	"hash/fnv"
 	"io"
 	"log"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Goroutine info from pprof API. See: https://github.com/DataDog/go-profiler-notes/blob/main/goroutine.md
@@ -15,13 +17,52 @@ import (
 // and https://github.com/golang/go/blob/go1.15.6/src/runtime/runtime2.go#L996-L1024
 type Goroutine struct {
 	ID             int64
-	Status         string // TODO: move known states to array and use slice for unknown
-	WaitSinceMin   int64
+	Status         Status
+	WaitSince      time.Duration // Runtime only reports full minutes
+	Annotations    []string      // Unknown annotations of the header such as "durable"
 	StackTrace     []StackFrame
 	CratedBy       *StackFrame // Only one frame long. Nill if not set
+	CreatorID      int64       // ID of the goroutine which created this one. Zero if not reported
 	LockedToThread bool
 }
 
+// Header returns the goroutine header line as printed by the runtime
+func (g Goroutine) Header() string {
+	state := string(g.Status)
+	if g.WaitSince > 0 {
+		state += fmt.Sprintf(", %d minutes", int64(g.WaitSince/time.Minute))
+	}
+	for _, a := range g.Annotations {
+		state += ", " + a
+	}
+	if g.LockedToThread {
+		state += ", locked to thread"
+	}
+	return fmt.Sprintf("goroutine %d [%s]:", g.ID, state)
+}
+
+// Text returns the goroutine in the original pprof debug=2 text format
+func (g Goroutine) Text() string {
+	var sb strings.Builder
+	sb.WriteString(g.Header())
+	sb.WriteByte('\n')
+	for _, s := range g.StackTrace {
+		sb.WriteString(s.Call())
+		sb.WriteByte('\n')
+		sb.WriteString(s.pos())
+	}
+	if g.CratedBy != nil {
+		sb.WriteString("created by ")
+		sb.WriteString(g.CratedBy.FuncName)
+		if g.CreatorID > 0 {
+			fmt.Fprintf(&sb, " in goroutine %d", g.CreatorID)
+		}
+		sb.WriteByte('\n')
+		sb.WriteString(g.CratedBy.pos())
+	}
+	return sb.String()
+}
+
 // StackContains returns true if string is included on one of the elements of the stack slice
 func StackContains(sf []StackFrame, subString string) bool {
 	for _, s := range sf {
@@ -35,14 +76,110 @@ func StackContains(sf []StackFrame, subString string) bool {
 // StackFrame contains the info for one stack frame
 // See: https://dev.to/mcaci/reading-stack-traces-in-go-3ah5
 type StackFrame struct {
-	FuncName string
+	FuncName string   // Full function name without arguments
+	Func     Func     // Decomposed FuncName
+	Args     []string // Raw argument words such as 0xc000010000 or {0x1, 0x2}. Nil for created by frames
 	File     string
 	Line     int32
-	Position *int // Relative stack position. Not mandatory
+	Position *int   // Relative stack position. Not mandatory
+	PC       uint64 // Program counter. Only set by the debug=1 format
 }
 
 func (s StackFrame) String() string {
-	return fmt.Sprintf("%s\n   file://%s#%d +0x%x", s.FuncName, s.File, s.Line, s.Position)
+	if s.Position == nil {
+		return fmt.Sprintf("%s\n   file://%s#%d", s.Call(), s.File, s.Line)
+	}
+	return fmt.Sprintf("%s\n   file://%s#%d +0x%x", s.Call(), s.File, s.Line, *s.Position)
+}
+
+// FrameID identifies a stack frame by function, file, line and offset. Arguments are ignored.
+// Frames without offset are distinct from frames with offset zero. Can be used as map key
+type FrameID struct {
+	FuncName  string
+	File      string
+	Line      int32
+	Offset    int
+	HasOffset bool
+}
+
+// ID returns the identity of the frame
+func (s StackFrame) ID() FrameID {
+	id := FrameID{FuncName: s.FuncName, File: s.File, Line: s.Line}
+	if s.Position != nil {
+		id.Offset = *s.Position
+		id.HasOffset = true
+	}
+	return id
+}
+
+func (id FrameID) String() string {
+	if !id.HasOffset {
+		return fmt.Sprintf("%s %s:%d", id.FuncName, id.File, id.Line)
+	}
+	return fmt.Sprintf("%s %s:%d +0x%x", id.FuncName, id.File, id.Line, id.Offset)
+}
+
+// Fingerprint identifies a stack by its function names only. Unlike StackID it is stable across builds
+// as long as the call structure does not change. Arguments, files, lines and offsets are ignored.
+// Leading and trailing frames of the runtime package such as runtime.gopark and runtime.main are skipped
+// since the profile formats differ in which of them they show. Stacks of runtime frames only keep all frames
+func Fingerprint(frames []StackFrame) string {
+	start, end := 0, len(frames)
+	for start < end && frames[start].Func.ImportPath == "runtime" {
+		start++
+	}
+	for end > start && frames[end-1].Func.ImportPath == "runtime" {
+		end--
+	}
+	if start == end {
+		start, end = 0, len(frames)
+	}
+	h := fnv.New64a()
+	for _, s := range frames[start:end] {
+		_, _ = io.WriteString(h, s.FuncName)
+		_, _ = h.Write([]byte{'\n'})
+	}
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+// StackID identifies the stack of the goroutine including the created by frame. Equal for goroutines with identical stacks
+func (g Goroutine) StackID() string {
+	var sb strings.Builder
+	for _, s := range g.StackTrace {
+		sb.WriteString(s.ID().String())
+		sb.WriteByte('\n')
+	}
+	if g.CratedBy != nil {
+		sb.WriteString("created by ")
+		sb.WriteString(g.CratedBy.ID().String())
+	}
+	return sb.String()
+}
+
+// Call returns the function name with arguments as printed in goroutine dumps
+func (s StackFrame) Call() string {
+	if s.Args == nil {
+		return s.FuncName
+	}
+	return fmt.Sprintf("%s(%s)", s.FuncName, strings.Join(s.Args, ", "))
+}
+
+// ShortName returns the function name without the import path prefix such as http.(*conn).serve
+func (s StackFrame) ShortName() string {
+	return s.FuncName[strings.LastIndex(s.FuncName, "/")+1:]
+}
+
+// Location returns the file and line of the frame as file:line
+func (s StackFrame) Location() string {
+	return fmt.Sprintf("%s:%d", s.File, s.Line)
+}
+
+// pos returns the position line of the frame in the pprof debug=2 format
+func (s StackFrame) pos() string {
+	if s.Position == nil {
+		return fmt.Sprintf("\t%s\n", s.Location())
+	}
+	return fmt.Sprintf("\t%s +0x%x\n", s.Location(), *s.Position)
 }
 
 // For example /usr/local/go/src/net/http/server.go:2969 +0x970
@@ -53,8 +190,16 @@ func ParseStackPos(text string) (fileName string, line int32, pos *int, err erro
 		err = fmt.Errorf("unexpected empty line")
 		return
 	}
+	// Tracebacks with GOTRACEBACK=system append the registers such as fp=0x... sp=0x... pc=0x...
+	if registers := strings.Index(text, " fp=0x"); registers >= 0 {
+		text = text[:registers]
+	}
 
 	fileLineSep := strings.LastIndex(text, ":")
+	if fileLineSep < 0 {
+		err = fmt.Errorf("expected file:line in %s", text)
+		return
+	}
 
 	fileName = text[:fileLineSep]
 
@@ -64,6 +209,10 @@ func ParseStackPos(text string) (fileName string, line int32, pos *int, err erro
 		// Cannot parse stack pos for text. Keep default of nill
 		lineStr = text[fileLineSep+1:]
 	} else {
+		if !strings.HasPrefix(text[linePosSep:], " +0x") {
+			err = fmt.Errorf("expected stack pos of form +0x... in %s", text)
+			return
+		}
 		posInt64, errParse := strconv.ParseInt(text[linePosSep+4:], 16, 64)
 		if errParse != nil {
 			err = fmt.Errorf("could parse stack pos %s to line int. Error: %s", text, errParse.Error())
@@ -84,7 +233,39 @@ func ParseStackPos(text string) (fileName string, line int32, pos *int, err erro
 	return
 }
 
+// parseWaitTime parses a wait time annotation such as "16 minutes". Returns false if part is no wait time
+func parseWaitTime(part string) (time.Duration, bool) {
+	value, unit, found := strings.Cut(part, " ")
+	if !found {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	var d time.Duration
+	switch strings.TrimSuffix(unit, "s") {
+	case "nanosecond":
+		d = time.Nanosecond
+	case "microsecond":
+		d = time.Microsecond
+	case "millisecond":
+		d = time.Millisecond
+	case "second":
+		d = time.Second
+	case "minute":
+		d = time.Minute
+	case "hour":
+		d = time.Hour
+	default:
+		return 0, false
+	}
+	return time.Duration(n) * d, true
+}
+
 // ParseHeader of stack trace. See: https://golang.org/src/runtime/traceback.go?s=30186:30213#L869
+// The state block starts with the status followed by any number of comma separated annotations in any order.
+// Wait time and "locked to thread" are parsed. All other annotations are kept as they are
 func ParseHeader(header string) (routine Goroutine, err error) {
 	if len(header) < 10 {
 		err = fmt.Errorf("expected header to begin with \"goroutine \" but len was < 10")
@@ -95,6 +276,11 @@ func ParseHeader(header string) (routine Goroutine, err error) {
 		return
 	}
 	separator := strings.Index(header[10:], " ")
+	stateStart := strings.Index(header, " [")
+	if separator < 0 || stateStart < 10+separator || !strings.HasSuffix(header, "]:") {
+		err = fmt.Errorf("expected header of form \"goroutine <id> [<status>]:\", but got: %s", header)
+		return
+	}
 
 	id, parseErr := strconv.ParseInt(header[10:10+separator], 10, 64)
 	if parseErr != nil {
@@ -103,60 +289,162 @@ func ParseHeader(header string) (routine Goroutine, err error) {
 	}
 
 	// Remove []:
-	fullState := header[12+separator : len(header)-2]
-	firstComma := strings.Index(fullState, ",")
-	var status string
-	lockedToThread := false
-	waitTimeMin := int64(0)
-	if firstComma < 0 {
-		status = fullState
-	} else {
-		status = fullState[:firstComma]
+	parts := strings.Split(header[stateStart+2:len(header)-2], ",")
+	routine = Goroutine{
+		Status: Status(strings.TrimSpace(parts[0])),
+		ID:     id,
+	}
+	if routine.Status == "" {
+		err = fmt.Errorf("empty status in header %s", header)
+		return
+	}
+	for _, part := range parts[1:] {
+		part = strings.TrimSpace(part)
+		if part == "locked to thread" {
+			routine.LockedToThread = true
+		} else if wait, ok := parseWaitTime(part); ok {
+			routine.WaitSince = wait
+		} else if part != "" {
+			routine.Annotations = append(routine.Annotations, part)
+		}
+	}
+	return
+}
 
-		parseWaitBlock := func(part string) {
-			if part == "locked to thread" {
-				lockedToThread = true
-			} else {
-				minUnitSep := strings.Index(part, " ")
-				waitTimeMin, parseErr = strconv.ParseInt(part[:minUnitSep], 10, 64)
-				if parseErr != nil {
-					err = fmt.Errorf("failed to parse minutes. Err: %s", parseErr.Error())
-					return
-				}
+// WriteStackFrame writes all goroutines in the pprof debug=2 text format which can be read by ParseStackFrame
+func WriteStackFrame(writer io.Writer, routines []Goroutine) error {
+	for i, r := range routines {
+		if i > 0 {
+			if _, err := io.WriteString(writer, "\n"); err != nil {
+				return err
 			}
 		}
-
-		sndComma := strings.Index(fullState[firstComma+1:], ",")
-		if sndComma > 0 {
-			parseWaitBlock(fullState[firstComma+2 : firstComma+sndComma+1])
-			parseWaitBlock(fullState[firstComma+sndComma+3:])
-		} else {
-			parseWaitBlock(fullState[firstComma+2:])
+		if _, err := io.WriteString(writer, r.Text()); err != nil {
+			return err
 		}
 	}
-	routine = Goroutine{
-		Status:         status,
-		ID:             id,
-		WaitSinceMin:   waitTimeMin,
-		LockedToThread: lockedToThread,
+	return nil
+}
+
+// ParseErrorKind describes which part of a goroutine dump could not be parsed
+type ParseErrorKind int
+
+// Kinds of parse errors
+const (
+	HeaderError ParseErrorKind = iota
+	FrameError
+	CreatedByError
+	UnexpectedEOFError
+	LabelsError
+)
+
+func (k ParseErrorKind) String() string {
+	switch k {
+	case HeaderError:
+		return "invalid header"
+	case FrameError:
+		return "invalid frame"
+	case CreatedByError:
+		return "invalid created by frame"
+	case UnexpectedEOFError:
+		return "unexpected end of file"
+	case LabelsError:
+		return "invalid labels"
 	}
-	return
+	return "unknown"
+}
+
+// ParseError of one line of a goroutine dump
+type ParseError struct {
+	Kind ParseErrorKind
+	Line int    // Line number starting at 1
+	Text string // Content of the line which could not be parsed
+	Err  error  // Cause. Nil for unexpected end of file
+}
+
+func (e *ParseError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("line %d: %s", e.Line, e.Kind)
+	}
+	return fmt.Sprintf("line %d: %s: %s", e.Line, e.Kind, e.Err.Error())
 }
 
-// ParseStackFrame reads full file and return all goroutines as slice
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// ParseStackFrame reads full file and return all goroutines as slice.
+// Parts which could not be parsed are logged and skipped
 func ParseStackFrame(reader io.Reader) (routines []Goroutine, err error) {
+	routines, skipped, err := ParseStackFrameErrors(reader)
+	for _, e := range skipped {
+		log.Print(e.Error())
+	}
+	return
+}
+
+// ParseStackFrameStrict reads full file and return all goroutines as slice.
+// Fails with a *ParseError on the first part which could not be parsed
+func ParseStackFrameStrict(reader io.Reader) (routines []Goroutine, err error) {
+	return parseStackFrame(reader, true, nil)
+}
+
+// ParseStackFrameErrors reads full file and return all goroutines as slice.
+// Parts which could not be parsed are skipped and returned as errors
+func ParseStackFrameErrors(reader io.Reader) (routines []Goroutine, skipped []*ParseError, err error) {
+	routines, err = parseStackFrame(reader, false, func(e *ParseError) {
+		skipped = append(skipped, e)
+	})
+	return
+}
+
+func parseStackFrame(reader io.Reader, strict bool, onError func(*ParseError)) (routines []Goroutine, err error) {
 	scanner := bufio.NewScanner(reader)
-	for scanner.Scan() {
+	lineNumber := 0
+	scan := func() bool {
+		if scanner.Scan() {
+			lineNumber++
+			return true
+		}
+		return false
+	}
+	var parseErr *ParseError
+	fail := func(kind ParseErrorKind, cause error) bool {
+		parseErr = &ParseError{Kind: kind, Line: lineNumber, Text: scanner.Text(), Err: cause}
+		if kind == UnexpectedEOFError {
+			parseErr.Text = ""
+		}
+		if strict {
+			return true
+		}
+		onError(parseErr)
+		return false
+	}
+
+	// Frames of a goroutine with an invalid header are skipped without reporting each line
+	skipping := false
+	for scan() {
 		line := scanner.Text()
+		if len(line) == 0 {
+			skipping = false
+			continue
+		}
+		if skipping && !strings.HasPrefix(line, "goroutine ") {
+			continue
+		}
+		skipping = false
 
 		routine, err := ParseHeader(line)
 		if err != nil {
-			log.Printf("Failed to parse routine header. Err: %s", err.Error())
+			if fail(HeaderError, err) {
+				return nil, parseErr
+			}
+			skipping = true
 			continue
 		}
 
 		routine.StackTrace = make([]StackFrame, 0, 8)
-		for scanner.Scan() {
+		for scan() {
 			traceLine := scanner.Text()
 
 			if len(traceLine) == 0 {
@@ -164,34 +452,54 @@ func ParseStackFrame(reader io.Reader) (routines []Goroutine, err error) {
 			}
 
 			if strings.HasPrefix(traceLine, "created by ") {
-				if !scanner.Scan() {
-					log.Println("Unexpected end of file")
+				if !scan() {
+					if fail(UnexpectedEOFError, nil) {
+						return nil, parseErr
+					}
 					continue
 				}
 
 				file, line, pos, err := ParseStackPos(scanner.Text())
 				if err != nil {
-					log.Printf("Failed to parse created by stack. Err: %s", err.Error())
+					if fail(CreatedByError, err) {
+						return nil, parseErr
+					}
 					continue
 				}
+				// Since Go 1.21 followed by " in goroutine <id>"
+				name := traceLine[11:]
+				if before, creator, found := strings.Cut(name, " in goroutine "); found {
+					if id, err := strconv.ParseInt(creator, 10, 64); err == nil {
+						name = before
+						routine.CreatorID = id
+					}
+				}
 				routine.CratedBy = &StackFrame{
-					FuncName: traceLine[11:],
+					FuncName: name,
+					Func:     ParseFunc(name),
 					File:     file,
 					Line:     line,
 					Position: pos,
 				}
 			} else {
-				if !scanner.Scan() {
-					log.Println("Unexpected end of file")
+				if !scan() {
+					if fail(UnexpectedEOFError, nil) {
+						return nil, parseErr
+					}
 					continue
 				}
 				file, line, pos, err := ParseStackPos(scanner.Text())
 				if err != nil {
-					log.Printf("Failed to parse stack. Err: %s", err.Error())
+					if fail(FrameError, err) {
+						return nil, parseErr
+					}
 					continue
 				}
+				name, args := ParseCall(traceLine)
 				frame := StackFrame{
-					FuncName: traceLine,
+					FuncName: name,
+					Func:     ParseFunc(name),
+					Args:     args,
 					File:     file,
 					Line:     line,
 					Position: pos,
@@ -3,6 +3,7 @@ package model_test
 import (
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/becheran/roumon/internal/model"
 	"github.com/stretchr/testify/assert"
@@ -75,22 +76,23 @@ func TestParseTrace(t *testing.T) {
 	// Routine 0
 	r0 := routines[0]
 	assert.Equal(t, int64(4431), r0.ID)
-	assert.Equal(t, "running", r0.Status)
-	assert.Equal(t, int64(0), r0.WaitSinceMin)
+	assert.Equal(t, model.StatusRunning, r0.Status)
+	assert.Equal(t, time.Duration(0), r0.WaitSince)
 	//created by net/http.(*Server).Serve
 	//	/usr/local/go/src/net/http/server.go:2969 +0x970
 	assert.Equal(t, "/usr/local/go/src/net/http/server.go", r0.CratedBy.File)
 	assert.Equal(t, int32(2969), r0.CratedBy.Line)
 	assert.Equal(t, 0x970, *r0.CratedBy.Position)
 	assert.Equal(t, "net/http.(*Server).Serve", r0.CratedBy.FuncName)
-	assert.Equal(t, "runtime/pprof.writeGoroutineStacks(0xe491c0, 0xc0001380e0, 0x0, 0x0)", r0.StackTrace[0].FuncName)
+	assert.Equal(t, "runtime/pprof.writeGoroutineStacks", r0.StackTrace[0].FuncName)
+	assert.Equal(t, []string{"0xe491c0", "0xc0001380e0", "0x0", "0x0"}, r0.StackTrace[0].Args)
 	assert.False(t, r0.LockedToThread)
 
 	// Routine 1
 	r1 := routines[1]
 	assert.Equal(t, int64(1), r1.ID)
-	assert.Equal(t, int64(16), r1.WaitSinceMin)
-	assert.Equal(t, "chan receive", r1.Status)
+	assert.Equal(t, 16*time.Minute, r1.WaitSince)
+	assert.Equal(t, model.StatusChanReceive, r1.Status)
 	assert.Nil(t, r1.CratedBy)
 	assert.False(t, r1.LockedToThread)
 
@@ -102,14 +104,15 @@ func TestParseTrace(t *testing.T) {
 	// Routine 3
 	r3 := routines[3]
 	assert.Equal(t, int64(35), r3.ID)
-	assert.Equal(t, "IO wait", r3.Status)
+	assert.Equal(t, model.StatusIOWait, r3.Status)
 	assert.Equal(t, "company/foo/bar/SecureTest/cmd/TestService/foo.Initfoo", r3.CratedBy.FuncName)
 	assert.Equal(t, "/home/user/dev/TestService/code/testapp/cmd/TestService/foo/foo_debug.go", r3.CratedBy.File)
 	assert.Equal(t, int32(21), r3.CratedBy.Line)
 	assert.Equal(t, 0x72, *r3.CratedBy.Position)
 	r3Stack := r3.StackTrace[len(r3.StackTrace)-1]
 	assert.Equal(t, "/home/user/dev/TestService/code/testapp/cmd/TestService/foo/foo_debug.go", r3Stack.File)
-	assert.Equal(t, "company/foo/bar/SecureTest/cmd/TestService/foo.Initfoo.func1()", r3Stack.FuncName)
+	assert.Equal(t, "company/foo/bar/SecureTest/cmd/TestService/foo.Initfoo.func1", r3Stack.FuncName)
+	assert.Equal(t, []string{}, r3Stack.Args)
 	assert.Equal(t, 0x5d, *r3Stack.Position)
 	assert.False(t, r3.LockedToThread)
 }
@@ -129,8 +132,8 @@ func TestParseLockedToThread(t *testing.T) {
 	// Routine 0
 	r0 := routines[0]
 	assert.Equal(t, int64(268), r0.ID)
-	assert.Equal(t, "runnable", r0.Status)
-	assert.Equal(t, int64(0), r0.WaitSinceMin)
+	assert.Equal(t, model.StatusRunnable, r0.Status)
+	assert.Equal(t, time.Duration(0), r0.WaitSince)
 	assert.True(t, r0.LockedToThread)
 }
 
@@ -139,6 +142,10 @@ func Test_ParseStackPos_Invalid(t *testing.T) {
 	assert.NotNil(t, err)
 	_, _, _, err = model.ParseStackPos("")
 	assert.NotNil(t, err)
+	_, _, _, err = model.ParseStackPos("no position")
+	assert.NotNil(t, err)
+	_, _, _, err = model.ParseStackPos("/tmp/main.go:10 +1")
+	assert.NotNil(t, err)
 }
 
 // StackContains returns true if string is included on one of the elements of the stack slice
@@ -161,6 +168,13 @@ func Test_ParseStackPos_Valid(t *testing.T) {
 	assert.Equal(t, "C:/Program Files/Go/src/runtime/syscall_windows.go", fileName)
 	assert.Equal(t, int32(356), line)
 	assert.Equal(t, 0xf2, *pos)
+
+	// Tracebacks with GOTRACEBACK=system
+	fileName, line, pos, err = model.ParseStackPos("/usr/local/go/src/runtime/chan.go:283 +0x3fc fp=0x3efad7c08f70 sp=0x3efad7c08f00 pc=0x418c9c")
+	assert.Nil(t, err)
+	assert.Equal(t, "/usr/local/go/src/runtime/chan.go", fileName)
+	assert.Equal(t, int32(283), line)
+	assert.Equal(t, 0x3fc, *pos)
 }
 
 func Test_ParseHeader_Invalid(t *testing.T) {
@@ -170,30 +184,185 @@ func Test_ParseHeader_Invalid(t *testing.T) {
 	assert.NotNil(t, err)
 	_, err = model.ParseHeader("goroutine0fd")
 	assert.NotNil(t, err)
+	_, err = model.ParseHeader("goroutine 12")
+	assert.NotNil(t, err)
+	_, err = model.ParseHeader("goroutine 12 []:")
+	assert.NotNil(t, err)
 }
 
 func Test_ParseHeader_Valid(t *testing.T) {
 	result, err := model.ParseHeader("goroutine 268 [runnable, locked to thread]:")
 	assert.Nil(t, err)
 	assert.Equal(t, int64(268), result.ID)
-	assert.Equal(t, "runnable", result.Status)
+	assert.Equal(t, model.StatusRunnable, result.Status)
 	assert.Equal(t, true, result.LockedToThread)
 
 	result, err = model.ParseHeader("goroutine 1 [chan receive, 16 minutes]:")
 	assert.Nil(t, err)
 	assert.Equal(t, int64(1), result.ID)
-	assert.Equal(t, "chan receive", result.Status)
-	assert.Equal(t, int64(16), result.WaitSinceMin)
+	assert.Equal(t, model.StatusChanReceive, result.Status)
+	assert.Equal(t, 16*time.Minute, result.WaitSince)
 	assert.Equal(t, false, result.LockedToThread)
 
+	result, err = model.ParseHeader("goroutine 7 gp=0x3efad7c76000 m=nil [chan send]:")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(7), result.ID)
+	assert.Equal(t, model.StatusChanSend, result.Status)
+
 	result, err = model.ParseHeader("goroutine 1 [chan receive, 16 minutes, locked to thread]:")
 	assert.Nil(t, err)
 	assert.Equal(t, int64(1), result.ID)
-	assert.Equal(t, "chan receive", result.Status)
-	assert.Equal(t, int64(16), result.WaitSinceMin)
+	assert.Equal(t, model.StatusChanReceive, result.Status)
+	assert.Equal(t, 16*time.Minute, result.WaitSince)
 	assert.Equal(t, true, result.LockedToThread)
 }
 
+func TestGoroutineText(t *testing.T) {
+	routines, err := model.ParseStackFrame(strings.NewReader(trace_1))
+	assert.Nil(t, err)
+	texts := make([]string, len(routines))
+	for i, r := range routines {
+		texts[i] = r.Text()
+	}
+	assert.Equal(t, trace_1+"\n", strings.Join(texts, "\n"))
+
+	routines, err = model.ParseStackFrame(strings.NewReader(trace_2))
+	assert.Nil(t, err)
+	assert.Equal(t, trace_2+"\n", routines[0].Text())
+}
+
+func TestWriteStackFrame(t *testing.T) {
+	routines, err := model.ParseStackFrame(strings.NewReader(trace_1))
+	assert.Nil(t, err)
+	var sb strings.Builder
+	assert.Nil(t, model.WriteStackFrame(&sb, routines))
+	assert.Equal(t, trace_1+"\n", sb.String())
+
+	parsed, err := model.ParseStackFrame(strings.NewReader(sb.String()))
+	assert.Nil(t, err)
+	assert.Equal(t, routines, parsed)
+}
+
+func TestStackFrameLocation(t *testing.T) {
+	sf := model.StackFrame{File: "/usr/local/go/src/net/http/server.go", Line: 2969}
+	assert.Equal(t, "/usr/local/go/src/net/http/server.go:2969", sf.Location())
+}
+
+var trace_invalid = `goroutine 1 [running]:
+main.main()
+	/tmp/main.go:10 +0x1d
+main.broken()
+	no position
+
+goroutine invalid
+main.other()
+	/tmp/main.go:20 +0x1d
+
+goroutine 2 [select]:
+main.worker()
+	/tmp/main.go:30 +0x1d
+created by main.main
+	/tmp/main.go:x`
+
+var trace_invalidHeader = `goroutine 1 [running
+main.main()
+	/tmp/main.go:10 +0x1d
+goroutine 2 [select]:
+main.worker()
+	/tmp/main.go:30 +0x1d`
+
+func TestParseErrors(t *testing.T) {
+	routines, skipped, err := model.ParseStackFrameErrors(strings.NewReader(trace_invalid))
+	assert.Nil(t, err)
+	assert.Len(t, routines, 2)
+	assert.Equal(t, int64(1), routines[0].ID)
+	assert.Len(t, routines[0].StackTrace, 1)
+	assert.Equal(t, int64(2), routines[1].ID)
+	assert.Nil(t, routines[1].CratedBy)
+
+	// Frames of the goroutine with the invalid header are not reported
+	assert.Len(t, skipped, 3)
+	assert.Equal(t, model.FrameError, skipped[0].Kind)
+	assert.Equal(t, 5, skipped[0].Line)
+	assert.Equal(t, "\tno position", skipped[0].Text)
+	assert.Equal(t, model.HeaderError, skipped[1].Kind)
+	assert.Equal(t, 7, skipped[1].Line)
+	assert.Equal(t, "goroutine invalid", skipped[1].Text)
+	assert.Equal(t, model.CreatedByError, skipped[2].Kind)
+	assert.Equal(t, 15, skipped[2].Line)
+	assert.Contains(t, skipped[2].Error(), "line 15: invalid created by frame")
+}
+
+func TestParseErrorsSkipToNextHeader(t *testing.T) {
+	routines, skipped, err := model.ParseStackFrameErrors(strings.NewReader(trace_invalidHeader))
+	assert.Nil(t, err)
+	assert.Len(t, skipped, 1)
+	assert.Equal(t, 1, skipped[0].Line)
+	if assert.Len(t, routines, 1) {
+		assert.Equal(t, int64(2), routines[0].ID)
+	}
+}
+
+func TestParseStrict(t *testing.T) {
+	routines, err := model.ParseStackFrameStrict(strings.NewReader(trace_1))
+	assert.Nil(t, err)
+	assert.Len(t, routines, 4)
+
+	routines, err = model.ParseStackFrameStrict(strings.NewReader(trace_invalid))
+	assert.Nil(t, routines)
+	var parseErr *model.ParseError
+	assert.ErrorAs(t, err, &parseErr)
+	assert.Equal(t, 5, parseErr.Line)
+	assert.Equal(t, model.FrameError, parseErr.Kind)
+}
+
+func TestParseUnexpectedEOF(t *testing.T) {
+	routines, skipped, err := model.ParseStackFrameErrors(strings.NewReader("goroutine 1 [running]:\nmain.main()"))
+	assert.Nil(t, err)
+	assert.Len(t, routines, 1)
+	assert.Len(t, skipped, 1)
+	assert.Equal(t, model.UnexpectedEOFError, skipped[0].Kind)
+	assert.Equal(t, 2, skipped[0].Line)
+}
+
+func Test_ParseHeader_Annotations(t *testing.T) {
+	tests := []struct {
+		header      string
+		status      model.Status
+		wait        time.Duration
+		locked      bool
+		annotations []string
+	}{
+		{"goroutine 1 [select, locked to thread, 3 minutes]:", model.StatusSelect, 3 * time.Minute, true, nil},
+		{"goroutine 1 [chan receive, 1 minutes, durable]:", model.StatusChanReceive, time.Minute, false, []string{"durable"}},
+		{"goroutine 1 [chan receive, durable, 2 hours, locked to thread, foo bar]:", model.StatusChanReceive, 2 * time.Hour, true, []string{"durable", "foo bar"}},
+		{"goroutine 1 [sleep, 30 seconds]:", model.StatusSleep, 30 * time.Second, false, nil},
+		{"goroutine 1 [sleep, 5 fortnights]:", model.StatusSleep, 0, false, []string{"5 fortnights"}},
+		{"goroutine 1 gp=0xc000002380 m=nil [chan receive (nil chan)]:", model.StatusChanReceiveNil, 0, false, nil},
+	}
+	for _, test := range tests {
+		result, err := model.ParseHeader(test.header)
+		assert.Nil(t, err, test.header)
+		assert.Equal(t, int64(1), result.ID, test.header)
+		assert.Equal(t, test.status, result.Status, test.header)
+		assert.Equal(t, test.wait, result.WaitSince, test.header)
+		assert.Equal(t, test.locked, result.LockedToThread, test.header)
+		assert.Equal(t, test.annotations, result.Annotations, test.header)
+	}
+}
+
+func Test_Header_RoundTrip(t *testing.T) {
+	for _, header := range []string{
+		"goroutine 1 [chan receive, 16 minutes, locked to thread]:",
+		"goroutine 2 [select, 1 minutes, durable]:",
+		"goroutine 3 [running]:",
+	} {
+		result, err := model.ParseHeader(header)
+		assert.Nil(t, err)
+		assert.Equal(t, header, result.Header())
+	}
+}
+
 func Benchmark_ParseTrace(b *testing.B) {
 	for n := 0; n < b.N; n++ {
 		model.ParseStackFrame(strings.NewReader(trace_1))
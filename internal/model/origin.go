@@ -0,0 +1,205 @@
+package model
+
+import (
+	"slices"
+	"strings"
+)
+
+// Origin of the code of a stack frame
+type Origin int
+
+// Frame origins
+const (
+	OriginRuntime    Origin = iota // Go runtime
+	OriginStdlib                   // Standard library in GOROOT
+	OriginThirdParty               // Dependencies from the module cache or vendor directory
+	OriginOwn                      // Code of the monitored application
+)
+
+func (o Origin) String() string {
+	switch o {
+	case OriginRuntime:
+		return "runtime"
+	case OriginStdlib:
+		return "stdlib"
+	case OriginThirdParty:
+		return "third-party"
+	}
+	return "own"
+}
+
+// Classifier determines the origin of stack frames
+type Classifier struct {
+	// Import path prefixes of own modules. If empty, all frames which are neither
+	// in GOROOT, the module cache nor a vendor directory are own
+	Modules []string
+	// GOROOT directory such as /usr/local/go. Set by Detect from the runtime frames of the dump.
+	// If empty, any path with a src element followed by a standard library package is in GOROOT
+	Goroot string
+}
+
+// Detect returns a classifier with the GOROOT and the own module prefixes derived from the goroutines if none are configured.
+// The deepest frame of each goroutine which is neither in the runtime, GOROOT, the module cache, a vendor directory
+// nor the main package determines the prefix such as github.com/user/app. Modules are unchanged if no goroutine has such a frame
+func (c Classifier) Detect(routines []Goroutine) Classifier {
+	if c.Goroot == "" {
+		c.Goroot = Goroot(routines)
+	}
+	if len(c.Modules) > 0 {
+		return c
+	}
+	detected := Classifier{Goroot: c.Goroot}
+	for _, r := range routines {
+		stack := r.StackTrace
+		if r.CratedBy != nil {
+			stack = append(stack[:len(stack):len(stack)], *r.CratedBy)
+		}
+		for i := len(stack) - 1; i >= 0; i-- {
+			path := stack[i].Func.ImportPath
+			if path == "" {
+				path = ParseFunc(stack[i].FuncName).ImportPath
+			}
+			if path == "" || path == "main" || c.Classify(stack[i]) != OriginOwn {
+				continue
+			}
+			if m := modulePrefix(path); !slices.Contains(detected.Modules, m) {
+				detected.Modules = append(detected.Modules, m)
+			}
+			break
+		}
+	}
+	if len(detected.Modules) == 0 {
+		return c
+	}
+	return detected
+}
+
+// Goroot returns the GOROOT directory of the first frame located in the runtime sources such as /usr/local/go
+// for /usr/local/go/src/runtime/proc.go. Empty if no frame of the goroutines is located there
+func Goroot(routines []Goroutine) string {
+	for _, r := range routines {
+		for _, f := range r.StackTrace {
+			path := f.Func.ImportPath
+			if path == "" {
+				path = ParseFunc(f.FuncName).ImportPath
+			}
+			file := strings.ReplaceAll(f.File, "\\", "/")
+			// Standard library functions such as time.Sleep are implemented in the runtime sources
+			if i := strings.LastIndex(file, "/src/runtime/"); i >= 0 && path != "" && isStdPath(path) {
+				return file[:i]
+			}
+		}
+	}
+	return ""
+}
+
+// modulePrefix returns the module part of an import path. Host, owner and repository for code hosts
+// such as github.com/user/app/pkg, host and path element for other domains such as go.uber.org/zap
+// and the first element for paths without domain
+func modulePrefix(path string) string {
+	elems := strings.Split(path, "/")
+	n := 1
+	switch {
+	case slices.Contains(codeHosts, elems[0]):
+		n = 3
+	case strings.Contains(elems[0], "."):
+		n = 2
+	}
+	return strings.Join(elems[:min(n, len(elems))], "/")
+}
+
+// Hosts with import paths of the form host/owner/repository
+var codeHosts = []string{"github.com", "gitlab.com", "bitbucket.org"}
+
+// NewClassifier for a comma separated list of own module prefixes. Empty for automatic detection with Detect
+func NewClassifier(modules string) Classifier {
+	var c Classifier
+	for _, m := range strings.Split(modules, ",") {
+		if m = strings.TrimSpace(m); m != "" {
+			c.Modules = append(c.Modules, strings.TrimSuffix(m, "/"))
+		}
+	}
+	return c
+}
+
+// Classify returns the origin of the frame
+func (c Classifier) Classify(f StackFrame) Origin {
+	path := f.Func.ImportPath
+	if path == "" {
+		path = ParseFunc(f.FuncName).ImportPath
+	}
+	file := strings.ReplaceAll(f.File, "\\", "/")
+
+	switch {
+	case path == "runtime" || strings.HasPrefix(path, "runtime/internal/") || strings.HasPrefix(path, "internal/runtime/"):
+		return OriginRuntime
+	case path == "main" || c.own(path):
+		return OriginOwn
+	case isGoroot(path, file, c.Goroot):
+		return OriginStdlib
+	case strings.Contains(file, "/pkg/mod/") || strings.Contains(file, "/vendor/") || strings.Contains(file, "@v"):
+		return OriginThirdParty
+	case len(c.Modules) > 0:
+		return OriginThirdParty
+	}
+	return OriginOwn
+}
+
+func (c Classifier) own(path string) bool {
+	for _, m := range c.Modules {
+		if path == m || strings.HasPrefix(path, m+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// isGoroot returns true for standard library packages located in goroot/src or trimmed with -trimpath.
+// Functions such as time.Sleep are implemented in the runtime package, so any standard library directory is accepted.
+// Without goroot any src directory followed by a standard library directory is accepted
+func isGoroot(path, file, goroot string) bool {
+	if path == "" || !isStdPath(path) {
+		return false
+	}
+	if strings.HasPrefix(file, path+"/") {
+		return true
+	}
+	if goroot != "" {
+		return strings.HasPrefix(file, goroot+"/src/")
+	}
+	if i := strings.LastIndex(file, "/src/"); i >= 0 {
+		return isStdPath(file[i+5:])
+	}
+	return false
+}
+
+// isStdPath returns true if the first element of the path contains no dot as all standard library import paths
+func isStdPath(path string) bool {
+	first, _, _ := strings.Cut(path, "/")
+	return !strings.Contains(first, ".")
+}
+
+// FirstOwnFrame returns the top most frame of the goroutine which belongs to the own code
+func (c Classifier) FirstOwnFrame(g Goroutine) (StackFrame, bool) {
+	for _, f := range g.StackTrace {
+		if c.Classify(f) == OriginOwn {
+			return f, true
+		}
+	}
+	if g.CratedBy != nil && c.Classify(*g.CratedBy) == OriginOwn {
+		return *g.CratedBy, true
+	}
+	return StackFrame{}, false
+}
+
+// NameFrame returns the frame which names the stack. The first own frame or the top frame if the stack has no own frame.
+// Returns false for an empty stack
+func (c Classifier) NameFrame(stack []StackFrame) (StackFrame, bool) {
+	if frame, ok := c.FirstOwnFrame(Goroutine{StackTrace: stack}); ok {
+		return frame, true
+	}
+	if len(stack) > 0 {
+		return stack[0], true
+	}
+	return StackFrame{}, false
+}
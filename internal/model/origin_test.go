@@ -0,0 +1,127 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/becheran/roumon/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassify(t *testing.T) {
+	auto := model.NewClassifier("")
+	configured := model.NewClassifier("example.com/app, example.com/lib/")
+	assert.Equal(t, []string{"example.com/app", "example.com/lib"}, configured.Modules)
+
+	tests := []struct {
+		funcName   string
+		file       string
+		auto       model.Origin
+		configured model.Origin
+	}{
+		{"runtime.gopark", "/usr/local/go/src/runtime/proc.go", model.OriginRuntime, model.OriginRuntime},
+		{"internal/runtime/syscall.Syscall6", "/usr/local/go/src/internal/runtime/syscall/asm_linux_amd64.s", model.OriginRuntime, model.OriginRuntime},
+		{"net/http.(*conn).serve", "/usr/local/go/src/net/http/server.go", model.OriginStdlib, model.OriginStdlib},
+		{"syscall.WSARecv", "C:\\Program Files\\Go\\src\\syscall\\zsyscall_windows.go", model.OriginStdlib, model.OriginStdlib},
+		{"vendor/golang.org/x/net/http2/hpack.(*Decoder).Write", "/usr/local/go/src/vendor/golang.org/x/net/http2/hpack/hpack.go", model.OriginStdlib, model.OriginStdlib},
+		{"net/http.(*conn).serve", "net/http/server.go", model.OriginStdlib, model.OriginStdlib},
+		{"github.com/gorilla/mux.(*Router).ServeHTTP", "/home/user/go/pkg/mod/github.com/gorilla/mux@v1.8.0/mux.go", model.OriginThirdParty, model.OriginThirdParty},
+		{"github.com/gorilla/mux.(*Router).ServeHTTP", "/app/vendor/github.com/gorilla/mux/mux.go", model.OriginThirdParty, model.OriginThirdParty},
+		{"example.com/app/server.Run", "/home/user/app/server/run.go", model.OriginOwn, model.OriginOwn},
+		{"example.com/lib.Do", "/home/user/lib/do.go", model.OriginOwn, model.OriginOwn},
+		{"example.com/other.Do", "/home/user/other/do.go", model.OriginOwn, model.OriginThirdParty},
+		{"main.main", "/home/user/app/main.go", model.OriginOwn, model.OriginOwn},
+	}
+	for _, tt := range tests {
+		t.Run(tt.funcName, func(t *testing.T) {
+			frame := model.StackFrame{FuncName: tt.funcName, File: tt.file}
+			assert.Equal(t, tt.auto, auto.Classify(frame))
+			assert.Equal(t, tt.configured, configured.Classify(frame))
+		})
+	}
+}
+
+func TestFirstOwnFrame(t *testing.T) {
+	c := model.NewClassifier("")
+	g := model.Goroutine{
+		StackTrace: []model.StackFrame{
+			{FuncName: "runtime.gopark", File: "/usr/local/go/src/runtime/proc.go"},
+			{FuncName: "net/http.(*conn).serve", File: "/usr/local/go/src/net/http/server.go"},
+			{FuncName: "main.handler", File: "/app/main.go"},
+			{FuncName: "main.main", File: "/app/main.go"},
+		},
+	}
+	f, ok := c.FirstOwnFrame(g)
+	assert.True(t, ok)
+	assert.Equal(t, "main.handler", f.FuncName)
+
+	g.StackTrace = g.StackTrace[:2]
+	_, ok = c.FirstOwnFrame(g)
+	assert.False(t, ok)
+
+	g.CratedBy = &model.StackFrame{FuncName: "main.main", File: "/app/main.go"}
+	f, ok = c.FirstOwnFrame(g)
+	assert.True(t, ok)
+	assert.Equal(t, "main.main", f.FuncName)
+}
+
+func TestClassifyLinkname(t *testing.T) {
+	c := model.NewClassifier("")
+	assert.Equal(t, model.OriginStdlib, c.Classify(model.StackFrame{FuncName: "time.Sleep", File: "/usr/local/go/src/runtime/time.go"}))
+	assert.Equal(t, model.OriginStdlib, c.Classify(model.StackFrame{FuncName: "internal/poll.runtime_pollWait", File: "/usr/local/go/src/runtime/netpoll.go"}))
+	assert.Equal(t, model.OriginOwn, c.Classify(model.StackFrame{FuncName: "example.com/app.Run", File: "/home/user/src/example.com/app/run.go"}))
+}
+
+func TestDetect(t *testing.T) {
+	routines := []model.Goroutine{
+		{StackTrace: []model.StackFrame{
+			{FuncName: "github.com/gorilla/mux.(*Router).ServeHTTP", File: "/home/user/go/pkg/mod/github.com/gorilla/mux@v1.8.0/mux.go"},
+			{FuncName: "example.com/other.Handle", File: "/home/user/other/handle.go"},
+			{FuncName: "example.com/app/server.(*Server).Run", File: "/home/user/app/server/run.go"},
+			{FuncName: "main.main", File: "/home/user/app/main.go"},
+		}},
+		{StackTrace: []model.StackFrame{
+			{FuncName: "net/http.(*conn).serve", File: "/usr/local/go/src/net/http/server.go"},
+		}},
+	}
+	c := model.NewClassifier("").Detect(routines)
+	assert.Equal(t, []string{"example.com/app"}, c.Modules)
+	assert.Equal(t, model.OriginThirdParty, c.Classify(routines[0].StackTrace[1]))
+	assert.Equal(t, model.OriginOwn, c.Classify(routines[0].StackTrace[2]))
+	assert.Equal(t, model.OriginOwn, c.Classify(routines[0].StackTrace[3]))
+
+	configured := model.NewClassifier("example.com/other")
+	assert.Equal(t, configured.Modules, configured.Detect(routines).Modules)
+	assert.Empty(t, model.NewClassifier("").Detect(routines[1:]).Modules)
+
+	routines[0].StackTrace[2].FuncName = "github.com/user/app/server.(*Server).Run"
+	assert.Equal(t, []string{"github.com/user/app"}, model.NewClassifier("").Detect(routines).Modules)
+}
+
+func TestGoroot(t *testing.T) {
+	routines := []model.Goroutine{
+		{StackTrace: []model.StackFrame{
+			{FuncName: "internal/poll.runtime_pollWait", File: "/usr/local/go/src/runtime/netpoll.go"},
+			{FuncName: "net/http.(*conn).serve", File: "/usr/local/go/src/net/http/server.go"},
+		}},
+		{StackTrace: []model.StackFrame{
+			{FuncName: "app.Serve", File: "/home/u/src/app/main.go"},
+			{FuncName: "main.main", File: "/home/u/src/app/main.go"},
+		}},
+	}
+	assert.Equal(t, "/usr/local/go", model.Goroot(routines))
+	assert.Empty(t, model.Goroot(routines[1:]))
+	assert.Equal(t, "C:/Program Files/Go", model.Goroot([]model.Goroutine{{StackTrace: []model.StackFrame{
+		{FuncName: "time.Sleep", File: "C:\\Program Files\\Go\\src\\runtime\\time.go"},
+	}}}))
+
+	c := model.NewClassifier("").Detect(routines)
+	assert.Equal(t, "/usr/local/go", c.Goroot)
+	assert.Equal(t, []string{"app"}, c.Modules)
+	assert.Equal(t, model.OriginStdlib, c.Classify(routines[0].StackTrace[1]))
+	assert.Equal(t, model.OriginOwn, c.Classify(routines[1].StackTrace[0]))
+	assert.Equal(t, "/home/u/src/app/main.go", model.ShortenPath(routines[1].StackTrace[0].File, model.PathGoroot, nil, c.Goroot))
+	assert.Equal(t, "$GOROOT/src/net/http/server.go", model.ShortenPath(routines[0].StackTrace[1].File, model.PathGoroot, nil, c.Goroot))
+
+	// Configured modules keep the detected GOROOT
+	assert.Equal(t, "/usr/local/go", model.NewClassifier("example.com/app").Detect(routines).Goroot)
+}
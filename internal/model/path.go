@@ -0,0 +1,115 @@
+package model
+
+import (
+	"path"
+	"slices"
+	"strings"
+)
+
+// PathMode defines how file paths of stack frames are shortened
+type PathMode int
+
+// Path modes
+const (
+	PathFull     PathMode = iota // Absolute build path
+	PathModule                   // Relative to the module root for own code
+	PathGoroot                   // Starting with $GOROOT for the standard library
+	PathModCache                 // Starting with module@version for dependencies from the module cache
+	PathBase                     // File name only
+	pathModeCount
+)
+
+func (m PathMode) String() string {
+	switch m {
+	case PathModule:
+		return "module relative"
+	case PathGoroot:
+		return "$GOROOT"
+	case PathModCache:
+		return "module@version"
+	case PathBase:
+		return "base"
+	}
+	return "full"
+}
+
+// Next returns the next path mode to cycle through all modes
+func (m PathMode) Next() PathMode {
+	return (m + 1) % pathModeCount
+}
+
+// ModuleRoot returns the directory of the module which contains the frame.
+// The root is found by matching the trailing elements of the package import path with the directory of the file.
+// Returns false if they have no element in common such as for the main package
+func ModuleRoot(f StackFrame) (string, bool) {
+	importPath := f.Func.ImportPath
+	if importPath == "" {
+		importPath = ParseFunc(f.FuncName).ImportPath
+	}
+	dir := path.Dir(strings.ReplaceAll(f.File, "\\", "/"))
+	pkgElems := strings.Split(importPath, "/")
+	dirElems := strings.Split(dir, "/")
+	matched := 0
+	for matched < len(pkgElems) && matched < len(dirElems) &&
+		pkgElems[len(pkgElems)-1-matched] == dirElems[len(dirElems)-1-matched] {
+		matched++
+	}
+	if matched == 0 || importPath == "main" {
+		return "", false
+	}
+	return strings.Join(dirElems[:len(dirElems)-matched], "/"), true
+}
+
+// ModuleRoots returns the distinct module roots of all own frames of the routines. Longest roots first
+func ModuleRoots(routines []Goroutine, c Classifier) []string {
+	var roots []string
+	add := func(f StackFrame) {
+		if c.Classify(f) != OriginOwn {
+			return
+		}
+		if root, ok := ModuleRoot(f); ok && !slices.Contains(roots, root) {
+			roots = append(roots, root)
+		}
+	}
+	for _, r := range routines {
+		for _, f := range r.StackTrace {
+			add(f)
+		}
+		if r.CratedBy != nil {
+			add(*r.CratedBy)
+		}
+	}
+	slices.SortFunc(roots, func(a, b string) int { return len(b) - len(a) })
+	return roots
+}
+
+// ShortenPath shortens the file path according to mode. Roots are the module roots of own code and goroot the GOROOT
+// directory of the dump, see Goroot. Paths which the mode does not apply to are returned unchanged
+func ShortenPath(file string, mode PathMode, roots []string, goroot string) string {
+	slashed := strings.ReplaceAll(file, "\\", "/")
+	switch mode {
+	case PathBase:
+		return path.Base(slashed)
+	case PathModule:
+		for _, root := range roots {
+			if rel, found := strings.CutPrefix(slashed, root+"/"); found && root != "" {
+				return rel
+			}
+		}
+	case PathModCache:
+		if i := strings.LastIndex(slashed, "/pkg/mod/"); i >= 0 {
+			return slashed[i+9:]
+		}
+	case PathGoroot:
+		if goroot != "" {
+			if rel, found := strings.CutPrefix(slashed, goroot+"/src/"); found {
+				return "$GOROOT/src/" + rel
+			}
+			break
+		}
+		if i := strings.LastIndex(slashed, "/src/"); i >= 0 && isStdPath(slashed[i+5:]) && !strings.Contains(slashed[:i], "/pkg/mod/") {
+			return "$GOROOT" + slashed[i:]
+		}
+	}
+	return file
+}
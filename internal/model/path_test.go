@@ -0,0 +1,68 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/becheran/roumon/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModuleRoot(t *testing.T) {
+	root, ok := model.ModuleRoot(model.StackFrame{
+		FuncName: "company/foo/bar/SecureTest/cmd/TestService/foo.Initfoo.func1",
+		File:     "/home/user/dev/TestService/code/testapp/cmd/TestService/foo/foo_debug.go",
+	})
+	assert.True(t, ok)
+	assert.Equal(t, "/home/user/dev/TestService/code/testapp", root)
+
+	_, ok = model.ModuleRoot(model.StackFrame{FuncName: "main.main", File: "/home/user/app/main.go"})
+	assert.False(t, ok)
+	_, ok = model.ModuleRoot(model.StackFrame{FuncName: "example.com/app.Run", File: "/home/user/other/run.go"})
+	assert.False(t, ok)
+}
+
+func TestModuleRoots(t *testing.T) {
+	routines := []model.Goroutine{{
+		StackTrace: []model.StackFrame{
+			{FuncName: "net/http.(*conn).serve", File: "/usr/local/go/src/net/http/server.go"},
+			{FuncName: "example.com/app/server.Run", File: "/home/user/myapp/server/run.go"},
+		},
+		CratedBy: &model.StackFrame{FuncName: "example.com/app/server/internal/pool.New", File: "/home/user/myapp/server/internal/pool/pool.go"},
+	}}
+	assert.Equal(t, []string{"/home/user/myapp"}, model.ModuleRoots(routines, model.NewClassifier("")))
+}
+
+func TestShortenPath(t *testing.T) {
+	roots := []string{"/home/user/app"}
+	tests := []struct {
+		file  string
+		mode  model.PathMode
+		short string
+	}{
+		{"/home/user/app/server/run.go", model.PathFull, "/home/user/app/server/run.go"},
+		{"/home/user/app/server/run.go", model.PathModule, "server/run.go"},
+		{"/home/user/app/server/run.go", model.PathGoroot, "/home/user/app/server/run.go"},
+		{"/home/user/app/server/run.go", model.PathBase, "run.go"},
+		{"/usr/local/go/src/net/http/server.go", model.PathGoroot, "$GOROOT/src/net/http/server.go"},
+		{"/usr/local/go/src/net/http/server.go", model.PathModule, "/usr/local/go/src/net/http/server.go"},
+		{"C:\\Program Files\\Go\\src\\syscall\\zsyscall_windows.go", model.PathGoroot, "$GOROOT/src/syscall/zsyscall_windows.go"},
+		{"C:\\Program Files\\Go\\src\\syscall\\zsyscall_windows.go", model.PathBase, "zsyscall_windows.go"},
+		{"/home/user/go/pkg/mod/github.com/gorilla/mux@v1.8.0/mux.go", model.PathModCache, "github.com/gorilla/mux@v1.8.0/mux.go"},
+		{"/home/user/go/pkg/mod/github.com/gorilla/mux@v1.8.0/mux.go", model.PathGoroot, "/home/user/go/pkg/mod/github.com/gorilla/mux@v1.8.0/mux.go"},
+		{"/usr/local/go/src/net/http/server.go", model.PathModCache, "/usr/local/go/src/net/http/server.go"},
+		{"/home/user/other/main.go", model.PathModule, "/home/user/other/main.go"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode.String()+" "+tt.file, func(t *testing.T) {
+			assert.Equal(t, tt.short, model.ShortenPath(tt.file, tt.mode, roots, ""))
+		})
+	}
+}
+
+func TestPathModeNext(t *testing.T) {
+	assert.Equal(t, model.PathModule, model.PathFull.Next())
+	assert.Equal(t, model.PathGoroot, model.PathModule.Next())
+	assert.Equal(t, model.PathModCache, model.PathGoroot.Next())
+	assert.Equal(t, model.PathBase, model.PathModCache.Next())
+	assert.Equal(t, model.PathFull, model.PathBase.Next())
+}
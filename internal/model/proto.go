@@ -0,0 +1,428 @@
+package model
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Protobuf wire types. See: https://protobuf.dev/programming-guides/encoding/#structure
+const (
+	wireVarint = 0
+	wireI64    = 1
+	wireLen    = 2
+	wireI32    = 5
+)
+
+// protoReader decodes the fields of one protobuf message
+type protoReader struct {
+	data []byte
+}
+
+// next returns the next field of the message. Value is set for varint and fixed size fields and data for length delimited fields
+func (r *protoReader) next() (field int, wireType int, value uint64, data []byte, err error) {
+	key, err := r.varint()
+	if err != nil {
+		return
+	}
+	field, wireType = int(key>>3), int(key&7)
+	switch wireType {
+	case wireVarint:
+		value, err = r.varint()
+	case wireI64:
+		if len(r.data) < 8 {
+			return 0, 0, 0, nil, io.ErrUnexpectedEOF
+		}
+		value = binary.LittleEndian.Uint64(r.data)
+		r.data = r.data[8:]
+	case wireI32:
+		if len(r.data) < 4 {
+			return 0, 0, 0, nil, io.ErrUnexpectedEOF
+		}
+		value = uint64(binary.LittleEndian.Uint32(r.data))
+		r.data = r.data[4:]
+	case wireLen:
+		var n uint64
+		n, err = r.varint()
+		if err != nil {
+			return
+		}
+		if n > uint64(len(r.data)) {
+			return 0, 0, 0, nil, io.ErrUnexpectedEOF
+		}
+		data = r.data[:n]
+		r.data = r.data[n:]
+	default:
+		err = fmt.Errorf("unsupported wire type %d of field %d", wireType, field)
+	}
+	return
+}
+
+func (r *protoReader) varint() (uint64, error) {
+	v, n := binary.Uvarint(r.data)
+	if n <= 0 {
+		return 0, fmt.Errorf("invalid varint")
+	}
+	r.data = r.data[n:]
+	return v, nil
+}
+
+// uint64s appends the values of a repeated integer field which is either packed or a single varint
+func uint64s(values []uint64, wireType int, value uint64, data []byte) ([]uint64, error) {
+	if wireType != wireLen {
+		return append(values, value), nil
+	}
+	packed := protoReader{data: data}
+	for len(packed.data) > 0 {
+		v, err := packed.varint()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// Messages of profile.proto which are needed for goroutine, block, mutex and threadcreate profiles.
+// See: https://github.com/google/pprof/blob/main/proto/profile.proto
+type (
+	protoValueType struct {
+		typ, unit int64
+	}
+	protoSample struct {
+		locationIDs []uint64
+		values      []uint64
+		labels      []protoLabel
+	}
+	protoLabel struct {
+		key, str, num, numUnit int64
+		numeric                bool // Set if num or numUnit is present. String labels may have the empty string at index 0
+	}
+	protoLocation struct {
+		address uint64
+		lines   []protoLine
+	}
+	protoLine struct {
+		functionID uint64
+		line       int64
+	}
+	protoFunction struct {
+		name, filename int64
+	}
+)
+
+// Profile is a pprof profile such as the goroutine, block, mutex or threadcreate profile
+type Profile struct {
+	SampleTypes []string // Type and unit of the values of each sample such as delay/nanoseconds
+	Samples     []Sample
+}
+
+// Sample is one stack of a profile
+type Sample struct {
+	Values     []int64 // One value per sample type
+	Labels     map[string]string
+	PCs        []uint64
+	StackTrace []StackFrame
+}
+
+// ValueIndex returns the index of the sample values with the type such as delay. Returns -1 if the profile has no such values
+func (p *Profile) ValueIndex(sampleType string) int {
+	for i, t := range p.SampleTypes {
+		if name, _, _ := strings.Cut(t, "/"); name == sampleType {
+			return i
+		}
+	}
+	return -1
+}
+
+// ParseProfile reads a goroutine profile in the protobuf format (debug=0) and returns one group per sample.
+// The profile may be gzip compressed
+func ParseProfile(reader io.Reader) (groups []Group, err error) {
+	profile, err := ParseSampleProfile(reader)
+	if err != nil {
+		return nil, err
+	}
+	groups = make([]Group, 0, len(profile.Samples))
+	for _, s := range profile.Samples {
+		g := Group{StackTrace: s.StackTrace, Labels: s.Labels, PCs: s.PCs}
+		if len(s.Values) > 0 {
+			g.Count = int(s.Values[0])
+		}
+		groups = append(groups, g)
+	}
+	return groups, nil
+}
+
+// ParseSampleProfile reads any profile in the protobuf format (debug=0) with all sample values.
+// The profile may be gzip compressed
+func ParseSampleProfile(reader io.Reader) (*Profile, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return &Profile{}, nil
+	}
+	if bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress profile. Err: %s", err.Error())
+		}
+		if data, err = io.ReadAll(gz); err != nil {
+			return nil, fmt.Errorf("failed to decompress profile. Err: %s", err.Error())
+		}
+	}
+
+	var sampleTypes []protoValueType
+	var samples []protoSample
+	locations := make(map[uint64]protoLocation)
+	functions := make(map[uint64]protoFunction)
+	var strs []string
+
+	r := protoReader{data: data}
+	for len(r.data) > 0 {
+		field, wireType, _, msg, err := r.next()
+		if err != nil {
+			return nil, fmt.Errorf("invalid profile. Err: %s", err.Error())
+		}
+		if wireType != wireLen {
+			continue
+		}
+		switch field {
+		case 1:
+			t, err := parseProtoValueType(msg)
+			if err != nil {
+				return nil, fmt.Errorf("invalid sample type. Err: %s", err.Error())
+			}
+			sampleTypes = append(sampleTypes, t)
+		case 2:
+			s, err := parseProtoSample(msg)
+			if err != nil {
+				return nil, fmt.Errorf("invalid sample. Err: %s", err.Error())
+			}
+			samples = append(samples, s)
+		case 4:
+			id, loc, err := parseProtoLocation(msg)
+			if err != nil {
+				return nil, fmt.Errorf("invalid location. Err: %s", err.Error())
+			}
+			locations[id] = loc
+		case 5:
+			id, fn, err := parseProtoFunction(msg)
+			if err != nil {
+				return nil, fmt.Errorf("invalid function. Err: %s", err.Error())
+			}
+			functions[id] = fn
+		case 6:
+			strs = append(strs, string(msg))
+		}
+	}
+
+	str := func(i int64) (string, error) {
+		if i < 0 || i >= int64(len(strs)) {
+			return "", fmt.Errorf("string index %d out of range", i)
+		}
+		return strs[i], nil
+	}
+
+	profile := &Profile{Samples: make([]Sample, 0, len(samples))}
+	for _, t := range sampleTypes {
+		name, err := str(t.typ)
+		if err != nil {
+			return nil, err
+		}
+		unit, err := str(t.unit)
+		if err != nil {
+			return nil, err
+		}
+		profile.SampleTypes = append(profile.SampleTypes, name+"/"+unit)
+	}
+	for _, s := range samples {
+		sample := Sample{StackTrace: []StackFrame{}}
+		for _, v := range s.values {
+			sample.Values = append(sample.Values, int64(v))
+		}
+		for _, l := range s.labels {
+			key, err := str(l.key)
+			if err != nil {
+				return nil, err
+			}
+			value, err := str(l.str)
+			if err != nil {
+				return nil, err
+			}
+			if l.numeric {
+				unit, err := str(l.numUnit)
+				if err != nil {
+					return nil, err
+				}
+				value = strconv.FormatInt(l.num, 10) + unit
+			}
+			if sample.Labels == nil {
+				sample.Labels = make(map[string]string)
+			}
+			sample.Labels[key] = value
+		}
+		for _, id := range s.locationIDs {
+			loc, ok := locations[id]
+			if !ok {
+				return nil, fmt.Errorf("unknown location %d", id)
+			}
+			sample.PCs = append(sample.PCs, loc.address)
+			// Inlined functions first. The last line is the caller
+			for _, line := range loc.lines {
+				fn, ok := functions[line.functionID]
+				if !ok {
+					return nil, fmt.Errorf("unknown function %d", line.functionID)
+				}
+				name, err := str(fn.name)
+				if err != nil {
+					return nil, err
+				}
+				file, err := str(fn.filename)
+				if err != nil {
+					return nil, err
+				}
+				sample.StackTrace = append(sample.StackTrace, StackFrame{
+					FuncName: name,
+					Func:     ParseFunc(name),
+					File:     file,
+					Line:     int32(line.line),
+					PC:       loc.address,
+				})
+			}
+		}
+		profile.Samples = append(profile.Samples, sample)
+	}
+	return profile, nil
+}
+
+func parseProtoValueType(data []byte) (t protoValueType, err error) {
+	r := protoReader{data: data}
+	for len(r.data) > 0 {
+		field, _, value, _, err := r.next()
+		if err != nil {
+			return t, err
+		}
+		switch field {
+		case 1:
+			t.typ = int64(value)
+		case 2:
+			t.unit = int64(value)
+		}
+	}
+	return t, nil
+}
+
+func parseProtoSample(data []byte) (s protoSample, err error) {
+	r := protoReader{data: data}
+	for len(r.data) > 0 {
+		field, wireType, value, msg, err := r.next()
+		if err != nil {
+			return s, err
+		}
+		switch field {
+		case 1:
+			s.locationIDs, err = uint64s(s.locationIDs, wireType, value, msg)
+		case 2:
+			s.values, err = uint64s(s.values, wireType, value, msg)
+		case 3:
+			var l protoLabel
+			l, err = parseProtoLabel(msg)
+			s.labels = append(s.labels, l)
+		}
+		if err != nil {
+			return s, err
+		}
+	}
+	return s, nil
+}
+
+func parseProtoLabel(data []byte) (l protoLabel, err error) {
+	r := protoReader{data: data}
+	for len(r.data) > 0 {
+		field, _, value, _, err := r.next()
+		if err != nil {
+			return l, err
+		}
+		switch field {
+		case 1:
+			l.key = int64(value)
+		case 2:
+			l.str = int64(value)
+		case 3:
+			l.num = int64(value)
+			l.numeric = true
+		case 4:
+			l.numUnit = int64(value)
+			l.numeric = true
+		}
+	}
+	return l, nil
+}
+
+func parseProtoLocation(data []byte) (id uint64, loc protoLocation, err error) {
+	r := protoReader{data: data}
+	for len(r.data) > 0 {
+		field, wireType, value, msg, err := r.next()
+		if err != nil {
+			return 0, loc, err
+		}
+		switch field {
+		case 1:
+			id = value
+		case 3:
+			loc.address = value
+		case 4:
+			if wireType != wireLen {
+				return 0, loc, fmt.Errorf("expected line message")
+			}
+			line, err := parseProtoLine(msg)
+			if err != nil {
+				return 0, loc, err
+			}
+			loc.lines = append(loc.lines, line)
+		}
+	}
+	return id, loc, nil
+}
+
+func parseProtoLine(data []byte) (line protoLine, err error) {
+	r := protoReader{data: data}
+	for len(r.data) > 0 {
+		field, _, value, _, err := r.next()
+		if err != nil {
+			return line, err
+		}
+		switch field {
+		case 1:
+			line.functionID = value
+		case 2:
+			line.line = int64(value)
+		}
+	}
+	return line, nil
+}
+
+func parseProtoFunction(data []byte) (id uint64, fn protoFunction, err error) {
+	r := protoReader{data: data}
+	for len(r.data) > 0 {
+		field, _, value, _, err := r.next()
+		if err != nil {
+			return 0, fn, err
+		}
+		switch field {
+		case 1:
+			id = value
+		case 2:
+			fn.name = int64(value)
+		case 4:
+			fn.filename = int64(value)
+		}
+	}
+	return id, fn, nil
+}
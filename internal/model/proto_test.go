@@ -0,0 +1,108 @@
+package model_test
+
+import (
+	"bytes"
+	"context"
+	"runtime"
+	"runtime/pprof"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/becheran/roumon/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func blockWithLabels(ready chan<- struct{}, done <-chan struct{}) {
+	pprof.Do(context.Background(), pprof.Labels("tenant", "acme", "route", "/api", "empty", ""), func(context.Context) {
+		ready <- struct{}{}
+		<-done
+	})
+}
+
+func TestParseProfile(t *testing.T) {
+	ready := make(chan struct{})
+	done := make(chan struct{})
+	defer close(done)
+	for i := 0; i < 3; i++ {
+		go blockWithLabels(ready, done)
+		<-ready
+	}
+
+	var buf bytes.Buffer
+	assert.Nil(t, pprof.Lookup("goroutine").WriteTo(&buf, 0))
+	groups, err := model.ParseProfile(&buf)
+	assert.Nil(t, err)
+	assert.True(t, model.Total(groups) >= 4)
+
+	var labeled *model.Group
+	for i, g := range groups {
+		if g.Labels["tenant"] == "acme" {
+			labeled = &groups[i]
+		}
+	}
+	if assert.NotNil(t, labeled) {
+		assert.Equal(t, 3, labeled.Count)
+		assert.Equal(t, map[string]string{"tenant": "acme", "route": "/api", "empty": ""}, labeled.Labels)
+		assert.True(t, model.StackContains(labeled.StackTrace, "model_test.blockWithLabels"))
+		for _, f := range labeled.StackTrace {
+			if strings.HasSuffix(f.FuncName, "blockWithLabels.func1") {
+				assert.True(t, strings.HasSuffix(f.File, "proto_test.go"))
+				assert.True(t, f.Line > 0)
+				assert.Equal(t, "model_test", f.Func.Package)
+				assert.NotZero(t, f.PC)
+			}
+		}
+	}
+}
+
+func TestParseProfileInvalid(t *testing.T) {
+	groups, err := model.ParseProfile(strings.NewReader(""))
+	assert.Nil(t, err)
+	assert.Empty(t, groups)
+
+	_, err = model.ParseProfile(strings.NewReader("goroutine 1 [running]:"))
+	assert.NotNil(t, err)
+
+	_, err = model.ParseProfile(bytes.NewReader([]byte{0x1f, 0x8b, 0x00}))
+	assert.NotNil(t, err)
+
+	// Sample with unknown location id 1
+	_, err = model.ParseProfile(bytes.NewReader([]byte{0x12, 0x03, 0x0a, 0x01, 0x01}))
+	assert.ErrorContains(t, err, "unknown location 1")
+}
+
+func TestParseSampleProfile(t *testing.T) {
+	runtime.SetBlockProfileRate(1)
+	defer runtime.SetBlockProfileRate(0)
+	c := make(chan struct{})
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(c)
+	}()
+	<-c
+
+	var buf bytes.Buffer
+	assert.Nil(t, pprof.Lookup("block").WriteTo(&buf, 0))
+	profile, err := model.ParseSampleProfile(&buf)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"contentions/count", "delay/nanoseconds"}, profile.SampleTypes)
+	assert.Equal(t, 1, profile.ValueIndex("delay"))
+	assert.Equal(t, -1, profile.ValueIndex("inuse_space"))
+
+	var found bool
+	for _, s := range profile.Samples {
+		if model.StackContains(s.StackTrace, "model_test.TestParseSampleProfile") {
+			found = true
+			assert.Len(t, s.Values, 2)
+			assert.True(t, s.Values[1] > 0)
+		}
+	}
+	assert.True(t, found)
+
+	buf.Reset()
+	assert.Nil(t, pprof.Lookup("threadcreate").WriteTo(&buf, 0))
+	profile, err = model.ParseSampleProfile(&buf)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"threadcreate/count"}, profile.SampleTypes)
+}
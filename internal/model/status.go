@@ -0,0 +1,260 @@
+package model
+
+import "strings"
+
+// Status of a goroutine. Either a scheduling state such as running or the reason why the goroutine waits.
+// See: https://github.com/golang/go/blob/go1.23.0/src/runtime/runtime2.go#L1065-L1145
+type Status string
+
+// Known goroutine states and wait reasons
+const (
+	StatusIdle             Status = "idle"
+	StatusRunnable         Status = "runnable"
+	StatusRunning          Status = "running"
+	StatusSyscall          Status = "syscall"
+	StatusWaiting          Status = "waiting"
+	StatusDead             Status = "dead"
+	StatusCopyStack        Status = "copystack"
+	StatusPreempted        Status = "preempted"
+	StatusGCAssistMarking  Status = "GC assist marking"
+	StatusIOWait           Status = "IO wait"
+	StatusChanReceiveNil   Status = "chan receive (nil chan)"
+	StatusChanSendNil      Status = "chan send (nil chan)"
+	StatusDumpingHeap      Status = "dumping heap"
+	StatusGarbageCollect   Status = "garbage collection"
+	StatusGCScan           Status = "garbage collection scan"
+	StatusPanicWait        Status = "panicwait"
+	StatusSelect           Status = "select"
+	StatusSelectNoCases    Status = "select (no cases)"
+	StatusGCAssistWait     Status = "GC assist wait"
+	StatusGCSweepWait      Status = "GC sweep wait"
+	StatusGCScavengeWait   Status = "GC scavenge wait"
+	StatusChanReceive      Status = "chan receive"
+	StatusChanSend         Status = "chan send"
+	StatusFinalizerWait    Status = "finalizer wait"
+	StatusForceGCIdle      Status = "force gc (idle)"
+	StatusSemacquire       Status = "semacquire"
+	StatusSleep            Status = "sleep"
+	StatusSyncCondWait     Status = "sync.Cond.Wait"
+	StatusSyncMutexLock    Status = "sync.Mutex.Lock"
+	StatusSyncRWMutexRLock Status = "sync.RWMutex.RLock"
+	StatusSyncRWMutexLock  Status = "sync.RWMutex.Lock"
+	StatusSyncWaitGroup    Status = "sync.WaitGroup.Wait"
+	StatusTraceReader      Status = "trace reader (blocked)"
+	StatusWaitForGCCycle   Status = "wait for GC cycle"
+	StatusGCWorkerIdle     Status = "GC worker (idle)"
+	StatusGCWorkerActive   Status = "GC worker (active)"
+	StatusDebugCall        Status = "debug call"
+	StatusGCMarkTerm       Status = "GC mark termination"
+	StatusStoppingTheWorld Status = "stopping the world"
+	StatusFlushProcCaches  Status = "flushing proc caches"
+	StatusTraceGoStatus    Status = "trace goroutine status"
+	StatusTraceProcStatus  Status = "trace proc status"
+	StatusPageTraceFlush   Status = "page trace flush"
+	StatusCoroutine        Status = "coroutine"
+	StatusGCWeakToStrong   Status = "GC weak to strong wait"
+	StatusSynctestRun      Status = "synctest.Run"
+	StatusSynctestWait     Status = "synctest.Wait"
+	StatusTimerGoroutine   Status = "timer goroutine (idle)"
+)
+
+// Category groups goroutine states by what the goroutine is doing or waiting for
+type Category int
+
+// Status categories in display order
+const (
+	CategoryRunning Category = iota
+	CategoryRunnable
+	CategoryChannel
+	CategoryLock
+	CategoryIO
+	CategorySleep
+	CategorySystem
+	CategoryOther
+)
+
+// Categories in display order
+var Categories = []Category{
+	CategoryRunning,
+	CategoryRunnable,
+	CategoryChannel,
+	CategoryLock,
+	CategoryIO,
+	CategorySleep,
+	CategorySystem,
+	CategoryOther,
+}
+
+// KnownStatuses in display order. Grouped by category
+var KnownStatuses = []Status{
+	StatusRunning,
+	StatusRunnable,
+	StatusPreempted,
+	StatusChanReceive,
+	StatusChanSend,
+	StatusSelect,
+	StatusChanReceiveNil,
+	StatusChanSendNil,
+	StatusSelectNoCases,
+	StatusSemacquire,
+	StatusSyncMutexLock,
+	StatusSyncRWMutexRLock,
+	StatusSyncRWMutexLock,
+	StatusSyncWaitGroup,
+	StatusSyncCondWait,
+	StatusIOWait,
+	StatusSyscall,
+	StatusSleep,
+	StatusTimerGoroutine,
+	StatusIdle,
+	StatusWaiting,
+	StatusDead,
+	StatusCopyStack,
+	StatusGCAssistMarking,
+	StatusDumpingHeap,
+	StatusGarbageCollect,
+	StatusGCScan,
+	StatusPanicWait,
+	StatusGCAssistWait,
+	StatusGCSweepWait,
+	StatusGCScavengeWait,
+	StatusFinalizerWait,
+	StatusForceGCIdle,
+	StatusTraceReader,
+	StatusWaitForGCCycle,
+	StatusGCWorkerIdle,
+	StatusGCWorkerActive,
+	StatusDebugCall,
+	StatusGCMarkTerm,
+	StatusStoppingTheWorld,
+	StatusFlushProcCaches,
+	StatusTraceGoStatus,
+	StatusTraceProcStatus,
+	StatusPageTraceFlush,
+	StatusCoroutine,
+	StatusGCWeakToStrong,
+	StatusSynctestRun,
+	StatusSynctestWait,
+}
+
+var statusCategories = map[Status]Category{
+	StatusRunning:          CategoryRunning,
+	StatusRunnable:         CategoryRunnable,
+	StatusPreempted:        CategoryRunnable,
+	StatusChanReceive:      CategoryChannel,
+	StatusChanSend:         CategoryChannel,
+	StatusSelect:           CategoryChannel,
+	StatusChanReceiveNil:   CategoryChannel,
+	StatusChanSendNil:      CategoryChannel,
+	StatusSelectNoCases:    CategoryChannel,
+	StatusSemacquire:       CategoryLock,
+	StatusSyncMutexLock:    CategoryLock,
+	StatusSyncRWMutexRLock: CategoryLock,
+	StatusSyncRWMutexLock:  CategoryLock,
+	StatusSyncWaitGroup:    CategoryLock,
+	StatusSyncCondWait:     CategoryLock,
+	StatusIOWait:           CategoryIO,
+	StatusSyscall:          CategoryIO,
+	StatusSleep:            CategorySleep,
+	StatusTimerGoroutine:   CategorySleep,
+}
+
+var statusOrder = func() map[Status]int {
+	order := make(map[Status]int, len(KnownStatuses))
+	for i, s := range KnownStatuses {
+		order[s] = i
+	}
+	return order
+}()
+
+// Known returns true if the status is one of the KnownStatuses
+func (s Status) Known() bool {
+	_, ok := statusOrder[s]
+	return ok
+}
+
+// Category of the status. Unknown states are in CategoryOther
+func (s Status) Category() Category {
+	if c, ok := statusCategories[s]; ok {
+		return c
+	}
+	if s.Known() {
+		return CategorySystem
+	}
+	// Runtime annotations such as "chan receive (synctest)"
+	if base, _, found := strings.Cut(string(s), " ("); found && Status(base) != s {
+		if c := Status(base).Category(); c != CategoryOther && c != CategorySystem {
+			return c
+		}
+	}
+	return CategoryOther
+}
+
+// Waiting returns false for goroutines which run, are ready to run or are in a system call. Only waiting goroutines have a wait time
+func (s Status) Waiting() bool {
+	switch s {
+	case StatusRunning, StatusRunnable, StatusPreempted, StatusSyscall, StatusIdle, StatusDead, StatusCopyStack:
+		return false
+	}
+	return true
+}
+
+// CompareStatus orders states by category and known states before unknown ones.
+// Returns a negative number if a < b, a positive number if a > b and zero if equal
+func CompareStatus(a, b Status) int {
+	if ca, cb := a.Category(), b.Category(); ca != cb {
+		return int(ca) - int(cb)
+	}
+	oa, knownA := statusOrder[a]
+	ob, knownB := statusOrder[b]
+	switch {
+	case knownA && knownB:
+		return oa - ob
+	case knownA:
+		return -1
+	case knownB:
+		return 1
+	}
+	return strings.Compare(string(a), string(b))
+}
+
+func (c Category) String() string {
+	switch c {
+	case CategoryRunning:
+		return "running"
+	case CategoryRunnable:
+		return "runnable"
+	case CategoryChannel:
+		return "channel"
+	case CategoryLock:
+		return "lock"
+	case CategoryIO:
+		return "IO"
+	case CategorySleep:
+		return "sleeping"
+	case CategorySystem:
+		return "system"
+	}
+	return "other"
+}
+
+// Short returns an abbreviation of at most four characters
+func (c Category) Short() string {
+	switch c {
+	case CategoryRunning:
+		return "run"
+	case CategoryRunnable:
+		return "rdy"
+	case CategoryChannel:
+		return "chan"
+	case CategoryLock:
+		return "lock"
+	case CategoryIO:
+		return "IO"
+	case CategorySleep:
+		return "slp"
+	case CategorySystem:
+		return "sys"
+	}
+	return "oth"
+}
@@ -0,0 +1,57 @@
+package model_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/becheran/roumon/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatusCategory(t *testing.T) {
+	tests := []struct {
+		status   model.Status
+		category model.Category
+	}{
+		{model.StatusRunning, model.CategoryRunning},
+		{model.StatusRunnable, model.CategoryRunnable},
+		{model.StatusChanReceive, model.CategoryChannel},
+		{model.StatusSelect, model.CategoryChannel},
+		{model.StatusSyncMutexLock, model.CategoryLock},
+		{model.StatusSyncWaitGroup, model.CategoryLock},
+		{model.StatusSemacquire, model.CategoryLock},
+		{model.StatusIOWait, model.CategoryIO},
+		{model.StatusSyscall, model.CategoryIO},
+		{model.StatusSleep, model.CategorySleep},
+		{model.StatusGCWorkerIdle, model.CategorySystem},
+		{model.StatusFinalizerWait, model.CategorySystem},
+		{"chan receive (synctest)", model.CategoryChannel},
+		{"something new", model.CategoryOther},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.category, test.status.Category(), test.status)
+	}
+	assert.True(t, model.StatusSelect.Known())
+	assert.False(t, model.Status("something new").Known())
+}
+
+func TestStatusWaiting(t *testing.T) {
+	assert.False(t, model.StatusRunning.Waiting())
+	assert.False(t, model.StatusRunnable.Waiting())
+	assert.False(t, model.StatusSyscall.Waiting())
+	assert.True(t, model.StatusChanReceive.Waiting())
+	assert.True(t, model.StatusIOWait.Waiting())
+	assert.True(t, model.Status("future wait reason").Waiting())
+}
+
+func TestCompareStatus(t *testing.T) {
+	states := []model.Status{"zzz", model.StatusSleep, "aaa", model.StatusGCWorkerIdle, model.StatusSelect, model.StatusChanReceive, model.StatusRunning}
+	slices.SortFunc(states, model.CompareStatus)
+	assert.Equal(t, []model.Status{model.StatusRunning, model.StatusChanReceive, model.StatusSelect, model.StatusSleep, model.StatusGCWorkerIdle, "aaa", "zzz"}, states)
+}
+
+func TestKnownStatusesCategorized(t *testing.T) {
+	for i := 1; i < len(model.KnownStatuses); i++ {
+		assert.LessOrEqual(t, model.KnownStatuses[i-1].Category(), model.KnownStatuses[i].Category(), model.KnownStatuses[i])
+	}
+}
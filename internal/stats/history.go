@@ -0,0 +1,98 @@
+package stats
+
+import "time"
+
+// Point contains the values of multiple named series at a point in time
+type Point struct {
+	Time   time.Time
+	Values map[string]float64
+}
+
+// History is a ring buffer of points with a fixed capacity. The oldest point is overwritten once the buffer is full
+type History struct {
+	points []Point
+	start  int
+	size   int
+}
+
+// NewHistory creates a new history which keeps up to capacity points
+func NewHistory(capacity int) *History {
+	return &History{
+		points: make([]Point, max(capacity, 1)),
+	}
+}
+
+// Add a new point. Points must be added in chronological order
+func (h *History) Add(p Point) {
+	end := (h.start + h.size) % len(h.points)
+	h.points[end] = p
+	if h.size < len(h.points) {
+		h.size++
+	} else {
+		h.start = (h.start + 1) % len(h.points)
+	}
+}
+
+// Len returns the number of stored points
+func (h *History) Len() int {
+	return h.size
+}
+
+// Latest returns the most recently added point
+func (h *History) Latest() (Point, bool) {
+	if h.size == 0 {
+		return Point{}, false
+	}
+	return h.points[(h.start+h.size-1)%len(h.points)], true
+}
+
+// Since returns all points which are not older than t in chronological order
+func (h *History) Since(t time.Time) []Point {
+	points := make([]Point, 0, h.size)
+	for i := 0; i < h.size; i++ {
+		p := h.points[(h.start+i)%len(h.points)]
+		if !p.Time.Before(t) {
+			points = append(points, p)
+		}
+	}
+	return points
+}
+
+// Downsample reduces the points to at most buckets values per series. Each bucket covers the same time span
+// between the first and last point and contains the maximum value of the points within the bucket.
+// Empty buckets repeat the value of the previous bucket
+func Downsample(points []Point, series []string, buckets int) map[string][]float64 {
+	n := min(buckets, len(points))
+	result := make(map[string][]float64, len(series))
+	if n <= 0 {
+		return result
+	}
+	for _, s := range series {
+		result[s] = make([]float64, n)
+	}
+
+	from := points[0].Time
+	span := points[len(points)-1].Time.Sub(from)
+	filled := make([]bool, n)
+	for _, p := range points {
+		idx := 0
+		if span > 0 {
+			idx = min(int(float64(p.Time.Sub(from))/float64(span)*float64(n)), n-1)
+		}
+		for _, s := range series {
+			if !filled[idx] || p.Values[s] > result[s][idx] {
+				result[s][idx] = p.Values[s]
+			}
+		}
+		filled[idx] = true
+	}
+
+	for idx := 1; idx < n; idx++ {
+		if !filled[idx] {
+			for _, s := range series {
+				result[s][idx] = result[s][idx-1]
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,66 @@
+package stats_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/becheran/roumon/internal/stats"
+	"github.com/stretchr/testify/assert"
+)
+
+func point(t time.Time, v float64) stats.Point {
+	return stats.Point{Time: t, Values: map[string]float64{"total": v}}
+}
+
+func TestHistoryRing(t *testing.T) {
+	h := stats.NewHistory(3)
+	_, empty := h.Latest()
+	assert.False(t, empty)
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		h.Add(point(start.Add(time.Duration(i)*time.Second), float64(i)))
+	}
+	assert.Equal(t, 3, h.Len())
+
+	latest, ok := h.Latest()
+	assert.True(t, ok)
+	assert.Equal(t, 4.0, latest.Values["total"])
+
+	points := h.Since(time.Time{})
+	assert.Len(t, points, 3)
+	assert.Equal(t, 2.0, points[0].Values["total"])
+	assert.Equal(t, 4.0, points[2].Values["total"])
+
+	points = h.Since(start.Add(3 * time.Second))
+	assert.Len(t, points, 2)
+	assert.Equal(t, 3.0, points[0].Values["total"])
+}
+
+func TestDownsample(t *testing.T) {
+	start := time.Now()
+	points := make([]stats.Point, 0, 10)
+	for i := 0; i < 10; i++ {
+		points = append(points, point(start.Add(time.Duration(i)*time.Second), float64(i%4)))
+	}
+
+	result := stats.Downsample(points, []string{"total", "missing"}, 5)
+	assert.Equal(t, []float64{1, 3, 1, 3, 1}, result["total"])
+	assert.Equal(t, []float64{0, 0, 0, 0, 0}, result["missing"])
+
+	// Less points than buckets
+	result = stats.Downsample(points[:3], []string{"total"}, 5)
+	assert.Equal(t, []float64{0, 1, 2}, result["total"])
+
+	assert.Empty(t, stats.Downsample(nil, []string{"total"}, 5))
+}
+
+func TestDownsampleGap(t *testing.T) {
+	start := time.Now()
+	points := []stats.Point{
+		point(start, 5),
+		point(start.Add(time.Second), 7),
+		point(start.Add(10*time.Second), 2),
+	}
+	result := stats.Downsample(points, []string{"total"}, 5)
+	assert.Equal(t, []float64{7, 7, 2}, result["total"])
+}
@@ -0,0 +1,94 @@
+package stats
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Sample is a single measurement at a point in time
+type Sample struct {
+	Time  time.Time
+	Value float64
+}
+
+// Summary of all samples within a time window
+type Summary struct {
+	Window time.Duration
+	Count  int
+	Min    float64
+	Max    float64
+	Mean   float64
+	P50    float64
+	P90    float64
+	P99    float64
+}
+
+// Stats keeps samples up to a maximum age and computes statistics over time windows
+type Stats struct {
+	maxAge  time.Duration
+	samples []Sample // Ordered by time
+}
+
+// New creates new statistics which keep samples for maxAge
+func New(maxAge time.Duration) *Stats {
+	return &Stats{
+		maxAge: maxAge,
+	}
+}
+
+// Add a new sample. Samples older than the max age are dropped
+func (s *Stats) Add(t time.Time, value float64) {
+	s.samples = append(s.samples, Sample{Time: t, Value: value})
+
+	cutoff := t.Add(-s.maxAge)
+	drop := sort.Search(len(s.samples), func(i int) bool {
+		return !s.samples[i].Time.Before(cutoff)
+	})
+	if drop > 0 {
+		s.samples = append(s.samples[:0], s.samples[drop:]...)
+	}
+}
+
+// Reset removes all samples
+func (s *Stats) Reset() {
+	s.samples = s.samples[:0]
+}
+
+// Summary computes the statistics of all samples which are not older than window relative to now
+func (s *Stats) Summary(now time.Time, window time.Duration) Summary {
+	cutoff := now.Add(-window)
+	first := sort.Search(len(s.samples), func(i int) bool {
+		return !s.samples[i].Time.Before(cutoff)
+	})
+
+	summary := Summary{Window: window, Count: len(s.samples) - first}
+	if summary.Count == 0 {
+		return summary
+	}
+
+	values := make([]float64, summary.Count)
+	sum := 0.0
+	for i, sample := range s.samples[first:] {
+		values[i] = sample.Value
+		sum += sample.Value
+	}
+	sort.Float64s(values)
+
+	summary.Min = values[0]
+	summary.Max = values[len(values)-1]
+	summary.Mean = sum / float64(len(values))
+	summary.P50 = percentile(values, 50)
+	summary.P90 = percentile(values, 90)
+	summary.P99 = percentile(values, 99)
+	return summary
+}
+
+// percentile of sorted values using the nearest-rank method
+func percentile(sorted []float64, p float64) float64 {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
@@ -0,0 +1,77 @@
+package stats_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/becheran/roumon/internal/stats"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSummaryEmpty(t *testing.T) {
+	s := stats.New(time.Hour)
+	summary := s.Summary(time.Now(), time.Minute)
+	assert.Equal(t, 0, summary.Count)
+	assert.Equal(t, time.Minute, summary.Window)
+}
+
+func TestSummaryMean(t *testing.T) {
+	s := stats.New(time.Hour)
+	start := time.Now()
+	for i, v := range []float64{10, 20, 30, 100} {
+		s.Add(start.Add(time.Duration(i)*time.Second), v)
+	}
+	summary := s.Summary(start.Add(3*time.Second), time.Minute)
+	assert.Equal(t, 4, summary.Count)
+	assert.Equal(t, 10.0, summary.Min)
+	assert.Equal(t, 100.0, summary.Max)
+	assert.Equal(t, 40.0, summary.Mean)
+	assert.Equal(t, 20.0, summary.P50)
+	assert.Equal(t, 100.0, summary.P90)
+	assert.Equal(t, 100.0, summary.P99)
+}
+
+func TestSummaryWindow(t *testing.T) {
+	s := stats.New(time.Hour)
+	start := time.Now()
+	for i := 0; i < 120; i++ {
+		s.Add(start.Add(time.Duration(i)*time.Second), float64(i))
+	}
+	now := start.Add(119 * time.Second)
+
+	lastMinute := s.Summary(now, time.Minute)
+	assert.Equal(t, 61, lastMinute.Count)
+	assert.Equal(t, 59.0, lastMinute.Min)
+	assert.Equal(t, 119.0, lastMinute.Max)
+	assert.Equal(t, 89.0, lastMinute.Mean)
+
+	all := s.Summary(now, time.Hour)
+	assert.Equal(t, 120, all.Count)
+	assert.Equal(t, 0.0, all.Min)
+	assert.Equal(t, 59.5, all.Mean)
+	assert.Equal(t, 59.0, all.P50)
+	assert.Equal(t, 107.0, all.P90)
+	assert.Equal(t, 118.0, all.P99)
+}
+
+func TestMaxAge(t *testing.T) {
+	s := stats.New(time.Minute)
+	start := time.Now()
+	s.Add(start, 1000)
+	s.Add(start.Add(2*time.Minute), 1)
+	summary := s.Summary(start.Add(2*time.Minute), time.Hour)
+	assert.Equal(t, 1, summary.Count)
+	assert.Equal(t, 1.0, summary.Max)
+}
+
+func TestReset(t *testing.T) {
+	s := stats.New(time.Hour)
+	now := time.Now()
+	s.Add(now, 5)
+	s.Reset()
+	assert.Equal(t, 0, s.Summary(now, time.Hour).Count)
+	s.Add(now, 7)
+	summary := s.Summary(now, time.Hour)
+	assert.Equal(t, 1, summary.Count)
+	assert.Equal(t, 7.0, summary.Mean)
+}
@@ -0,0 +1,76 @@
+package ui
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/becheran/roumon/internal/baseline"
+	"github.com/becheran/roumon/internal/model"
+)
+
+// checkBaseline compares the stack groups of the latest snapshot with the baseline
+func (ui *UI) checkBaseline(groups []model.Group) {
+	if ui.baseline == nil {
+		return
+	}
+	ui.violations = ui.baseline.Check(groups)
+	ui.uncovered = baseline.Uncovered(ui.violations)
+	ui.updateLegend()
+}
+
+// baselineBadge returns a warning for the legend if the latest snapshot is not covered by the baseline
+func (ui *UI) baselineBadge() string {
+	if len(ui.violations) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("[! %d stacks not in baseline](fg:white,bg:red) | ", len(ui.violations))
+}
+
+// baselineMark returns a marker for list rows of stacks which are not covered by the baseline
+func (ui *UI) baselineMark(stack []model.StackFrame) string {
+	if len(ui.uncovered) == 0 {
+		return ""
+	}
+	if _, ok := ui.uncovered[model.Fingerprint(stack)]; ok {
+		return "[!](fg:white,bg:red) "
+	}
+	return ""
+}
+
+// baselineDetails returns the baseline violation of the stack for the details view
+func (ui *UI) baselineDetails(stack []model.StackFrame) string {
+	if ui.baseline == nil {
+		return ""
+	}
+	if v, ok := ui.uncovered[model.Fingerprint(stack)]; ok {
+		return fmt.Sprintf("Baseline: [%s](fg:red,mod:bold)\n\n", escape(v.String(ui.classifier)))
+	}
+	return "Baseline: [covered](fg:green)\n\n"
+}
+
+// saveBaseline writes a baseline of the latest snapshot to a timestamped file and uses it from now on
+func (ui *UI) saveBaseline() {
+	groups := ui.groups
+	if !ui.grouped() {
+		groups = model.GroupByStack(ui.origData)
+	}
+	b := baseline.FromGroups(groups, ui.classifier)
+	if err := os.MkdirAll(ui.opts.SnapshotDir, 0755); err != nil {
+		log.Print(err.Error())
+		ui.status.Text = fmt.Sprintf("Failed to create snapshot dir: %s", err.Error())
+		return
+	}
+	path := filepath.Join(ui.opts.SnapshotDir, fmt.Sprintf("roumon-baseline-%s.txt", time.Now().Format("20060102-150405.000")))
+	if err := b.Save(path); err != nil {
+		log.Print(err.Error())
+		ui.status.Text = err.Error()
+		return
+	}
+	ui.baseline = b
+	ui.checkBaseline(groups)
+	ui.updateList()
+	ui.status.Text = fmt.Sprintf("Saved baseline of %d stacks to %s", len(b.Entries), path)
+}
@@ -0,0 +1,69 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/becheran/roumon/internal/analysis"
+	"github.com/gizak/termui/v3/widgets"
+
+	termui "github.com/gizak/termui/v3"
+)
+
+func newChannelView() *widgets.List {
+	view := widgets.NewList()
+	view.Title = "Channel waits"
+	view.BorderStyle.Fg = termui.ColorMagenta
+	view.TextStyle = termui.NewStyle(termui.ColorWhite)
+	view.SelectedRowStyle = termui.NewStyle(termui.ColorBlack, termui.ColorMagenta)
+	view.PaddingTop = padding
+	view.PaddingRight = padding
+	view.PaddingLeft = padding
+	view.PaddingBottom = padding
+	return view
+}
+
+// updateChannelView shows the channels with the most blocked goroutines as a table of senders and receivers
+func (ui *UI) updateChannelView() {
+	ui.channelView.Rows = ui.channelView.Rows[:0]
+	if ui.groupedFormat {
+		ui.channelView.Title = "Channel waits"
+		ui.channelView.Rows = append(ui.channelView.Rows, "Channel waits can only be shown with -format=stacks")
+		return
+	}
+
+	channels := analysis.ChannelWaits(ui.origData)
+	waiting := 0
+	for _, c := range channels {
+		waiting += c.Waiting()
+	}
+	ui.channelView.Title = fmt.Sprintf("Channel waits (%d channels, %d blocked goroutines, Esc: close)", len(channels), waiting)
+	ui.channelView.Rows = append(ui.channelView.Rows, fmt.Sprintf("[%7s  %9s  %7s  %-12s  %-34s  %s](mod:bold)", "Senders", "Receivers", "Selects", "Longest wait", "Channel", "Call site"))
+	for _, c := range channels {
+		wait := formatWait(c.LongestWait)
+		if wait == "" {
+			wait = "<1m"
+		}
+		site := fmt.Sprintf("%s %s", escape(c.Site.ShortName()), c.Site.Location())
+		if c.Address == 0 {
+			site = escape(c.Site.ShortName())
+		}
+		row := fmt.Sprintf("%7d  %9d  %7d  %-12s  %-34s  %s", len(c.Senders), len(c.Receivers), len(c.Selects), wait, escape(c.Name()), site)
+		// Idle receivers such as workers waiting for jobs are common. Senders blocked for long are likely leaks
+		if c.StuckSenders(ui.opts.DeadlockAfter) {
+			row = fmt.Sprintf("[%s](fg:red) [senders stuck](fg:white,bg:red)", row)
+		}
+		ui.channelView.Rows = append(ui.channelView.Rows, row)
+		for _, ids := range []struct {
+			name    string
+			waiters []int64
+		}{{"senders", c.Senders}, {"receivers", c.Receivers}, {"selects", c.Selects}} {
+			if len(ids.waiters) > 0 {
+				ui.channelView.Rows = append(ui.channelView.Rows, fmt.Sprintf("%9s %s %s", "", ids.name, analysis.JoinIDs(ids.waiters, maxWaiterIDs)))
+			}
+		}
+	}
+	if len(channels) == 0 {
+		ui.channelView.Rows = append(ui.channelView.Rows, "No goroutines are blocked on a channel")
+	}
+	ui.channelView.SelectedRow = min(ui.channelView.SelectedRow, len(ui.channelView.Rows)-1)
+}
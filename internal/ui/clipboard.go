@@ -0,0 +1,62 @@
+package ui
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Clipboard modes
+const (
+	ClipboardAuto  = "auto"  // Use OSC 52 if the terminal supports it, else write to file
+	ClipboardOSC52 = "osc52" // Always use the OSC 52 terminal escape sequence
+	ClipboardFile  = "file"  // Always write to the clipboard file
+)
+
+// clipboard copies text via the OSC 52 terminal escape sequence which also works via SSH.
+// See: https://invisible-island.net/xterm/ctlseqs/ctlseqs.html#h3-Operating-System-Commands
+type clipboard struct {
+	mode string
+	file string
+	out  io.Writer
+}
+
+func newClipboard(mode, file string) *clipboard {
+	if mode == "" {
+		mode = ClipboardAuto
+	}
+	return &clipboard{
+		mode: mode,
+		file: file,
+		out:  os.Stdout,
+	}
+}
+
+// osc52Supported guesses if the terminal understands OSC 52. There is no reliable way to query it.
+func osc52Supported() bool {
+	term := os.Getenv("TERM")
+	return term != "" && term != "dumb" && term != "linux"
+}
+
+// copy text to clipboard and return a message describing where the text went
+func (c *clipboard) copy(text string) (string, error) {
+	useOSC52 := c.mode == ClipboardOSC52 || (c.mode == ClipboardAuto && osc52Supported())
+	if !useOSC52 {
+		if err := os.WriteFile(c.file, []byte(text), 0644); err != nil {
+			return "", fmt.Errorf("failed to write clipboard file. Err: %s", err.Error())
+		}
+		return fmt.Sprintf("written to %s", c.file), nil
+	}
+
+	seq := fmt.Sprintf("\x1b]52;c;%s\x07", base64.StdEncoding.EncodeToString([]byte(text)))
+	if os.Getenv("TMUX") != "" {
+		// tmux passthrough. Escape characters within the sequence need to be doubled
+		seq = fmt.Sprintf("\x1bPtmux;%s\x1b\\", strings.ReplaceAll(seq, "\x1b", "\x1b\x1b"))
+	}
+	if _, err := io.WriteString(c.out, seq); err != nil {
+		return "", fmt.Errorf("failed to write to terminal. Err: %s", err.Error())
+	}
+	return "copied to clipboard", nil
+}
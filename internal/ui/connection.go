@@ -0,0 +1,82 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/becheran/roumon/internal/client"
+)
+
+// connection keeps the state of the connection to the pprof server
+type connection struct {
+	url        string
+	err        error         // Error of the latest scrape
+	lastOK     client.Scrape // Latest successful scrape
+	metricsErr error         // Error of the latest runtime metrics fetch
+}
+
+// formatBytes returns a human readable size such as 12.3 KiB
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+// updateConnection stores the scrape and updates the connection status bar
+func (ui *UI) updateConnection(scrape client.Scrape) {
+	ui.conn.url = scrape.URL
+	ui.conn.err = scrape.Err
+	// Runtime metrics are only part of some scrapes
+	if scrape.Metrics != nil || scrape.MetricsErr != nil {
+		ui.conn.metricsErr = scrape.MetricsErr
+	}
+	if scrape.Err == nil {
+		ui.conn.lastOK = scrape
+		ui.parseErrors = scrape.ParseErrors
+		ui.updateLegend()
+	}
+	ui.updateConnectionBar()
+}
+
+// updateConnectionBar refreshes the connection status bar
+func (ui *UI) updateConnectionBar() {
+	if ui.conn.url == "" {
+		ui.connBar.Text = " Connecting..."
+		return
+	}
+
+	last := ui.conn.lastOK
+	state := "[connected](fg:green)"
+	if ui.conn.err != nil {
+		state = "[disconnected](fg:red)"
+	}
+	since := "never"
+	if !last.Time.IsZero() {
+		since = fmt.Sprintf("%s ago", time.Since(last.Time).Truncate(time.Second))
+	}
+	parseErrors := fmt.Sprintf("%d", len(last.ParseErrors))
+	if len(last.ParseErrors) > 0 {
+		parseErrors = fmt.Sprintf("[%d](fg:yellow)", len(last.ParseErrors))
+	}
+	ui.connBar.Text = fmt.Sprintf(" %s | %s | Last scrape: %s | Latency: %s | Size: %s | Goroutines: %d | Parse errors: %s",
+		escape(ui.conn.url),
+		state,
+		since,
+		last.Latency.Round(time.Millisecond),
+		formatBytes(last.Size),
+		last.Total(),
+		parseErrors)
+	if ui.conn.err != nil {
+		ui.connBar.Text += fmt.Sprintf(" | [%s](fg:red)", escape(ui.conn.err.Error()))
+	}
+	if ui.conn.metricsErr != nil {
+		ui.connBar.Text += fmt.Sprintf(" | [%s](fg:yellow)", escape(ui.conn.metricsErr.Error()))
+	}
+}
@@ -0,0 +1,88 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/becheran/roumon/internal/analysis"
+	"github.com/becheran/roumon/internal/model"
+	"github.com/gizak/termui/v3/widgets"
+
+	termui "github.com/gizak/termui/v3"
+)
+
+func newDeadlockView() *widgets.Paragraph {
+	view := widgets.NewParagraph()
+	view.Title = "Deadlocks"
+	view.TextStyle.Fg = termui.ColorWhite
+	view.BorderStyle.Fg = termui.ColorRed
+	view.PaddingTop = padding
+	view.PaddingRight = padding
+	view.PaddingLeft = padding
+	view.PaddingBottom = padding
+	return view
+}
+
+// checkDeadlocks searches all goroutines of the latest snapshot for likely deadlocks. Ignored goroutines are included,
+// since a running goroutine which is hidden still keeps the others alive
+func (ui *UI) checkDeadlocks() {
+	// Profile formats have no states and wait times
+	if ui.groupedFormat {
+		ui.deadlocks = nil
+		return
+	}
+	ui.deadlocks = analysis.Deadlocks(ui.scrape.Routines, ui.opts.DeadlockAfter)
+}
+
+// deadlockBadge returns a warning for the legend if likely deadlocks were found
+func (ui *UI) deadlockBadge() string {
+	if len(ui.deadlocks) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("[! %d possible deadlocks (Ctrl-D)](fg:white,bg:red) | ", len(ui.deadlocks))
+}
+
+// updateDeadlockView lists the findings with the involved goroutines
+func (ui *UI) updateDeadlockView() {
+	var sb strings.Builder
+	switch {
+	case ui.groupedFormat:
+		sb.WriteString("Deadlocks can only be detected with -format=stacks\n")
+	case len(ui.deadlocks) == 0:
+		sb.WriteString(fmt.Sprintf("No likely deadlocks. Goroutines must be blocked for at least %s\n", formatWindow(ui.opts.DeadlockAfter)))
+	}
+
+	routines := make(map[int64]model.Goroutine, len(ui.scrape.Routines))
+	for _, r := range ui.scrape.Routines {
+		routines[r.ID] = r
+	}
+	// Lines of routines are limited by the height of the view
+	maxRoutines := max(ui.deadlockView.Dy()-8-2*len(ui.deadlocks), len(ui.deadlocks))
+	perFinding := max(maxRoutines/max(len(ui.deadlocks), 1), 1)
+	for _, f := range ui.deadlocks {
+		sb.WriteString(fmt.Sprintf("[%s](fg:red,mod:bold)\n", f.String()))
+		for i, id := range f.Routines {
+			if i == perFinding {
+				sb.WriteString(fmt.Sprintf("  ... and %d more\n", len(f.Routines)-perFinding))
+				break
+			}
+			r := routines[id]
+			site := ""
+			if target, ok := analysis.WaitTarget(r); ok && target.Site.FuncName != "" {
+				site = fmt.Sprintf(" %s %s", escape(target.Site.ShortName()), target.Site.Location())
+			} else if frame, ok := ui.classifier.FirstOwnFrame(r); ok {
+				site = fmt.Sprintf(" %s %s", escape(frame.ShortName()), frame.Location())
+			}
+			if wait := formatWait(r.WaitSince); wait != "" {
+				site = " " + wait + site
+			}
+			sb.WriteString(fmt.Sprintf("  %5d [%s](fg:%s)%s\n", id, r.Status, categoryColors[r.Status.Category()], site))
+		}
+		sb.WriteByte('\n')
+	}
+	if !ui.groupedFormat {
+		sb.WriteString("Cycles are only found for locks. The pprof endpoint hides the runtime frames with channel addresses\n")
+	}
+	sb.WriteString("\nPress any key to continue")
+	ui.deadlockView.Text = sb.String()
+}
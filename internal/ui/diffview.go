@@ -0,0 +1,104 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/becheran/roumon/internal/diff"
+	"github.com/becheran/roumon/internal/model"
+	"github.com/gizak/termui/v3/widgets"
+
+	termui "github.com/gizak/termui/v3"
+)
+
+// Style colors of the diff changes. Growth is a potential leak
+var changeColors = map[diff.Change]string{
+	diff.Appeared:    "red",
+	diff.Grew:        "yellow",
+	diff.Shrank:      "cyan",
+	diff.Disappeared: "green",
+}
+
+// ShowDiff shows the diff report in fullscreen until the user quits
+func ShowDiff(report diff.Report, c model.Classifier) error {
+	if err := termui.Init(); err != nil {
+		return fmt.Errorf("failed to initialize termui. Err: %s", err.Error())
+	}
+	defer termui.Close()
+
+	list := widgets.NewList()
+	list.Title = fmt.Sprintf("Goroutines: %d -> %d (%+d) | Unchanged stacks: %d", report.Before, report.After, report.After-report.Before, report.Unchanged)
+	list.TextStyle = termui.NewStyle(termui.ColorWhite)
+	list.SelectedRowStyle.Fg = termui.ColorBlack
+	list.SelectedRowStyle.Bg = termui.ColorGreen
+	for _, e := range report.Entries {
+		list.Rows = append(list.Rows, fmt.Sprintf("[%-11s %+6d](fg:%s) %6d -> %-6d %s", e.Change, e.Delta, changeColors[e.Change], e.Before, e.After, escape(e.Name(c))))
+	}
+	if len(list.Rows) == 0 {
+		list.Rows = []string{"No changes"}
+	}
+
+	details := widgets.NewParagraph()
+	details.Title = "Stack"
+	details.PaddingLeft = padding
+	details.PaddingTop = padding
+
+	legend := widgets.NewParagraph()
+	legend.Border = false
+	legend.TextStyle.Fg = termui.ColorGreen
+	legend.Text = "Arrows: Select | q, F10: Quit"
+
+	grid := termui.NewGrid()
+	grid.Set(
+		termui.NewRow(1.0/2, list),
+		termui.NewRow(1.0/2, details),
+	)
+
+	resize := func(width, height int) {
+		grid.SetRect(0, 0, width, height-1)
+		legend.SetRect(-1, height-2, width+1, height+1)
+	}
+	update := func() {
+		if list.SelectedRow >= len(report.Entries) {
+			details.Text = ""
+			return
+		}
+		e := report.Entries[list.SelectedRow]
+		text := ""
+		if len(e.Labels) > 0 {
+			text += fmt.Sprintf("Labels: %s\n\n", escape(model.FormatLabels(e.Labels)))
+		}
+		for _, f := range e.StackTrace {
+			text += fmt.Sprintf("%s\n   %s\n", escape(f.FuncName), f.Location())
+		}
+		details.Text = text
+	}
+
+	resize(termui.TerminalDimensions())
+	update()
+	termui.Render(legend, grid)
+	for e := range termui.PollEvents() {
+		switch e.ID {
+		case "q", "<C-c>", "<F10>", "<Escape>":
+			return nil
+		case "<Down>":
+			list.ScrollDown()
+		case "<Up>":
+			list.ScrollUp()
+		case "<PageDown>":
+			list.ScrollPageDown()
+		case "<PageUp>":
+			list.ScrollPageUp()
+		case "<Home>":
+			list.ScrollTop()
+		case "<End>":
+			list.ScrollBottom()
+		case "<Resize>":
+			if r, ok := e.Payload.(termui.Resize); ok {
+				resize(r.Width, r.Height)
+			}
+		}
+		update()
+		termui.Render(legend, grid)
+	}
+	return nil
+}
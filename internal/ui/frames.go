@@ -0,0 +1,62 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/becheran/roumon/internal/model"
+)
+
+// hiddenOrigin returns true if the frame is hidden from the details while stdlib frames are hidden
+func hiddenOrigin(o model.Origin) bool {
+	return o == model.OriginRuntime || o == model.OriginStdlib
+}
+
+// formatTrace formats the stack frames for the details view. Consecutive hidden frames are collapsed into one line
+func (ui *UI) formatTrace(frames []model.StackFrame) string {
+	trace := ""
+	hidden := 0
+	for _, f := range frames {
+		if ui.hideStdlib && hiddenOrigin(ui.classifier.Classify(f)) {
+			hidden++
+			continue
+		}
+		if hidden > 0 {
+			trace += fmt.Sprintf("  ... %d runtime/stdlib frames\n", hidden)
+			hidden = 0
+		}
+		trace += fmt.Sprintf("  %s\n", ui.frameText(f))
+	}
+	if hidden > 0 {
+		trace += fmt.Sprintf("  ... %d runtime/stdlib frames\n", hidden)
+	}
+	return trace
+}
+
+// frameText formats the frame with the file path shortened according to the selected path mode
+func (ui *UI) frameText(f model.StackFrame) string {
+	if ui.pathMode == model.PathFull {
+		return escape(f.String())
+	}
+	return escape(fmt.Sprintf("%s\n   %s:%d", f.Call(), model.ShortenPath(f.File, ui.pathMode, ui.moduleRoots, ui.classifier.Goroot), f.Line))
+}
+
+// toggleStdlib hides or shows runtime and standard library frames in the details
+func (ui *UI) toggleStdlib() {
+	ui.hideStdlib = !ui.hideStdlib
+	ui.updateDetailsTitle()
+	ui.updateList()
+}
+
+// cyclePathMode switches to the next file path shortening mode of the details
+func (ui *UI) cyclePathMode() {
+	ui.pathMode = ui.pathMode.Next()
+	ui.updateDetailsTitle()
+	ui.updateList()
+}
+
+func (ui *UI) updateDetailsTitle() {
+	ui.details.Title = fmt.Sprintf("Details (%s paths)", ui.pathMode)
+	if ui.hideStdlib {
+		ui.details.Title = fmt.Sprintf("Details (%s paths, stdlib hidden)", ui.pathMode)
+	}
+}
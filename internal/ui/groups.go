@@ -0,0 +1,240 @@
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/becheran/roumon/internal/model"
+
+	termui "github.com/gizak/termui/v3"
+)
+
+// Maximum number of groups in the bar chart
+const maxChartGroups = 6
+
+// grouped returns true if identical stacks are shown as one list entry
+func (ui *UI) grouped() bool {
+	return ui.groupView || ui.groupedFormat
+}
+
+// cycleGrouping switches between the list of goroutines, the list of identical stacks and the lists grouped by each label key
+func (ui *UI) cycleGrouping() {
+	keys := ui.labelKeys()
+	switch i := slices.Index(keys, ui.groupLabel); {
+	case !ui.grouped():
+		ui.setGrouping("")
+	case ui.groupLabel == "" && len(keys) > 0:
+		ui.setGrouping(keys[0])
+	case ui.groupLabel != "" && i >= 0 && i+1 < len(keys):
+		ui.setGrouping(keys[i+1])
+	case ui.groupedFormat:
+		ui.setGrouping("")
+	default:
+		ui.groupView = false
+		ui.groupLabel = ""
+		ui.list.SelectedRow = 0
+		ui.updateList()
+	}
+}
+
+// setGrouping groups the list by identical stacks or by the value of the label key if not empty
+func (ui *UI) setGrouping(labelKey string) {
+	if !ui.groupedFormat && !ui.groupView {
+		ui.groups = model.GroupByStack(ui.origData)
+	}
+	ui.groupView = true
+	ui.groupLabel = labelKey
+	ui.list.SelectedRow = 0
+	ui.updateList()
+}
+
+// groupFrame returns the frame which represents the group in the list
+func (ui *UI) groupFrame(g model.Group) (model.StackFrame, bool) {
+	return ui.classifier.NameFrame(g.StackTrace)
+}
+
+// groupMatches returns true if the filter text is part of the stack or labels of the group
+// or if the labels match a label:key=value filter
+func groupMatches(g model.Group, filterText string) bool {
+	if f, ok := model.ParseLabelFilter(filterText); ok {
+		return f.Match(g.Labels)
+	}
+	filterText = strings.ToLower(filterText)
+	if model.StackContains(g.StackTrace, filterText) {
+		return true
+	}
+	for k, v := range g.Labels {
+		if strings.Contains(strings.ToLower(k+"="+v), filterText) {
+			return true
+		}
+	}
+	for _, r := range g.Routines {
+		if strings.Contains(strings.ToLower(string(r.Status)), filterText) {
+			return true
+		}
+	}
+	return false
+}
+
+// updateGroupList shows the groups of identical stacks in the list and the selected group in the details
+func (ui *UI) updateGroupList() {
+	if ui.filter.Text == "" || !ui.filtered {
+		ui.filteredGroups = ui.groups
+	} else {
+		ui.filteredGroups = make([]model.Group, 0)
+		for _, g := range ui.groups {
+			if groupMatches(g, ui.filter.Text) {
+				ui.filteredGroups = append(ui.filteredGroups, g)
+			}
+		}
+	}
+	if ui.groupLabel != "" {
+		ui.updateLabelGroupList()
+		return
+	}
+
+	ui.list.Rows = make([]string, len(ui.filteredGroups))
+	for i, g := range ui.filteredGroups {
+		row := ui.baselineMark(g.StackTrace) + fmt.Sprintf("[%5d x](fg:green)", g.Count)
+		if frame, ok := ui.groupFrame(g); ok {
+			row += " " + escape(frame.ShortName())
+		} else {
+			row += " (no frames)"
+		}
+		ui.list.Rows[i] = row
+	}
+
+	if len(ui.filteredGroups) == 0 {
+		ui.list.SelectedRow = 0
+		ui.details.Text = ""
+		ui.list.Title = "Groups (0/0) by count"
+		return
+	}
+	ui.list.SelectedRow = min(max(ui.list.SelectedRow, 0), len(ui.filteredGroups)-1)
+
+	g := ui.filteredGroups[ui.list.SelectedRow]
+	labels := ""
+	if len(g.Labels) > 0 {
+		labels = fmt.Sprintf("Labels: [%s](mod:bold)\n\n", escape(model.FormatLabels(g.Labels)))
+	}
+	routines := ""
+	if len(g.Routines) > 0 {
+		statusCount := make(map[model.Status]int)
+		ids := make([]string, 0, min(len(g.Routines), 20))
+		for i, r := range g.Routines {
+			statusCount[r.Status]++
+			if i < cap(ids) {
+				ids = append(ids, fmt.Sprintf("%d", r.ID))
+			}
+		}
+		if len(g.Routines) > len(ids) {
+			ids = append(ids, "...")
+		}
+		states := make([]string, 0, len(statusCount))
+		for _, s := range model.KnownStatuses {
+			if statusCount[s] > 0 {
+				states = append(states, fmt.Sprintf("%s: %d", s, statusCount[s]))
+				delete(statusCount, s)
+			}
+		}
+		for s, n := range statusCount {
+			states = append(states, fmt.Sprintf("%s: %d", s, n))
+		}
+		routines = fmt.Sprintf("Status: [%s](mod:bold)\n\nIDs: %s\n\n", strings.Join(states, ", "), strings.Join(ids, ", "))
+	}
+	ui.details.Text = fmt.Sprintf("Goroutines: [%d](mod:bold)\n\n%s%s%sTrace:\n%s",
+		g.Count,
+		ui.baselineDetails(g.StackTrace),
+		labels,
+		routines,
+		ui.formatTrace(g.StackTrace))
+
+	ui.list.Title = fmt.Sprintf("Groups (%d/%d) by count", ui.list.SelectedRow+1, len(ui.list.Rows))
+}
+
+// selectedGroup returns the currently selected group of the group view
+func (ui *UI) selectedGroup() (model.Group, bool) {
+	if ui.groupLabel != "" {
+		return model.Group{}, false
+	}
+	if ui.list.SelectedRow < 0 || ui.list.SelectedRow >= len(ui.filteredGroups) {
+		return model.Group{}, false
+	}
+	return ui.filteredGroups[ui.list.SelectedRow], true
+}
+
+// copySelectedGroup copies the selected group in the given format ("stack", "json" or "location") to the clipboard
+func (ui *UI) copySelectedGroup(format string) {
+	g, ok := ui.selectedGroup()
+	if !ok {
+		ui.status.Text = "Nothing selected"
+		return
+	}
+
+	var text string
+	switch format {
+	case "json":
+		data, err := json.MarshalIndent(g, "", "  ")
+		if err != nil {
+			ui.status.Text = fmt.Sprintf("Failed to encode group: %s", err.Error())
+			return
+		}
+		text = string(data)
+	case "location":
+		if len(g.StackTrace) == 0 {
+			ui.status.Text = "Group has no stack frames"
+			return
+		}
+		text = g.StackTrace[0].Location()
+	default:
+		text = g.Text()
+	}
+
+	msg, err := ui.clipboard.copy(text)
+	if err != nil {
+		log.Print(err.Error())
+		ui.status.Text = err.Error()
+		return
+	}
+	ui.status.Text = fmt.Sprintf("Group of %d goroutines %s %s", g.Count, format, msg)
+}
+
+// updateGroupChart shows the largest groups in the bar chart if the status of goroutines is unknown
+func (ui *UI) updateGroupChart() {
+	n := min(len(ui.groups), maxChartGroups)
+	data := make([]float64, n)
+	labels := make([]string, n)
+	legend := ""
+	for i, g := range ui.groups[:n] {
+		data[i] = float64(g.Count)
+		labels[i] = fmt.Sprintf("#%d", i+1)
+		name := "?"
+		if frame, ok := ui.groupFrame(g); ok {
+			name = escape(frame.ShortName())
+		}
+		legend += fmt.Sprintf("#%d: %s\n", i+1, name)
+	}
+	ui.barchart.Title = "Largest groups"
+	ui.barchart.Data = data
+	ui.barchart.Labels = labels
+	ui.barchart.BarColors = []termui.Color{termui.ColorGreen}
+	ui.barchartLegend.Text = legend
+}
+
+// saveGroupSnapshot writes the displayed groups to a file. Only the filtered groups are written if filtered is set
+func (ui *UI) saveGroupSnapshot(filtered bool) {
+	groups := ui.groups
+	if filtered {
+		groups = ui.filteredGroups
+	}
+	path, err := saveGroupSnapshot(ui.opts.SnapshotDir, ui.opts.SnapshotFormat, groups)
+	if err != nil {
+		log.Print(err.Error())
+		ui.status.Text = err.Error()
+		return
+	}
+	ui.status.Text = fmt.Sprintf("Saved %d groups to %s", len(groups), path)
+}
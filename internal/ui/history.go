@@ -0,0 +1,232 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/becheran/roumon/internal/client"
+	"github.com/becheran/roumon/internal/model"
+	"github.com/becheran/roumon/internal/stats"
+
+	termui "github.com/gizak/termui/v3"
+)
+
+const totalSeries = "total"
+
+// Series of the runtime metrics. Plotted next to the goroutine history on the same time axis
+const (
+	heapSeries    = "heap MiB"
+	threadsSeries = "threads"
+	gcSeries      = "GC count"
+)
+
+var metricSeries = []string{heapSeries, threadsSeries, gcSeries}
+
+// Colors of the runtime metric series
+var metricColors = []termui.Color{colorOrange, colorPurple, colorSky}
+
+// Time spans of the history plot
+var zoomLevels = []time.Duration{time.Minute, 5 * time.Minute, 15 * time.Minute, time.Hour, 3 * time.Hour, 6 * time.Hour}
+
+// Colors of the history series. The first color is used for the total count
+var seriesColors = []termui.Color{
+	termui.ColorGreen, termui.ColorRed, termui.ColorYellow, termui.ColorBlue, termui.ColorMagenta, termui.ColorCyan, termui.ColorWhite,
+	colorOrange, colorPurple, colorSky,
+}
+
+func newMetricPlot() (*timePlot, *seriesLegend) {
+	plot := newTimePlot()
+	plot.AxesColor = termui.ColorWhite
+	plot.PaddingTop = padding
+	plot.PaddingRight = padding
+	plot.PaddingLeft = padding
+	plot.PaddingBottom = padding
+	plot.BorderRight = false
+	return plot, newSeriesLegend()
+}
+
+// histSeries is the goroutine count history of one status
+type histSeries struct {
+	name   string
+	hidden bool
+}
+
+// addHistory adds the total and the count per status of routines at time t to the history
+func (ui *UI) addHistory(t time.Time, routines []model.Goroutine, total int) {
+	counts := map[string]float64{totalSeries: float64(total)}
+	for _, r := range routines {
+		counts[string(r.Status)]++
+	}
+	ui.history.Add(stats.Point{Time: t, Values: counts})
+
+	for name := range counts {
+		if !ui.hasHistSeries(name) {
+			ui.histSeries = append(ui.histSeries, histSeries{name: name})
+		}
+	}
+	// Total first and statuses ordered by category to keep the legend stable
+	sort.SliceStable(ui.histSeries, func(i, j int) bool {
+		if ui.histSeries[i].name == totalSeries || ui.histSeries[j].name == totalSeries {
+			return ui.histSeries[i].name == totalSeries
+		}
+		return model.CompareStatus(model.Status(ui.histSeries[i].name), model.Status(ui.histSeries[j].name)) < 0
+	})
+}
+
+// addMetrics adds the runtime metrics fetched at time t to the metrics history
+func (ui *UI) addMetrics(t time.Time, metrics client.Metrics) {
+	values := map[string]float64{
+		heapSeries: float64(metrics.HeapInuse) / (1 << 20),
+		gcSeries:   float64(metrics.NumGC),
+	}
+	if metrics.Threads > 0 {
+		values[threadsSeries] = float64(metrics.Threads)
+	}
+	ui.metricHist.Add(stats.Point{Time: t, Values: values})
+}
+
+func (ui *UI) hasHistSeries(name string) bool {
+	for _, s := range ui.histSeries {
+		if s.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// selectNextHistSeries moves the legend selection to the next series
+func (ui *UI) selectNextHistSeries() {
+	if len(ui.histSeries) == 0 {
+		return
+	}
+	ui.selectedSeries = (ui.selectedSeries + 1) % len(ui.histSeries)
+	ui.updateHistPlot()
+}
+
+// toggleHistSeries hides or shows the selected series
+func (ui *UI) toggleHistSeries() {
+	if ui.selectedSeries >= len(ui.histSeries) {
+		return
+	}
+	ui.histSeries[ui.selectedSeries].hidden = !ui.histSeries[ui.selectedSeries].hidden
+	ui.updateHistPlot()
+}
+
+// zoomHist changes the time span of the history plot. Positive steps zoom in
+func (ui *UI) zoomHist(step int) {
+	ui.zoom = min(max(ui.zoom-step, 0), len(zoomLevels)-1)
+	ui.updateHistPlot()
+}
+
+// updateHistPlot downsamples the history of the visible series to the plot width and updates the legend
+func (ui *UI) updateHistPlot() {
+	var points []stats.Point
+	if latest, ok := ui.history.Latest(); ok {
+		points = ui.history.Since(latest.Time.Add(-zoomLevels[ui.zoom]))
+	}
+	names := make([]string, len(ui.histSeries))
+	for i, s := range ui.histSeries {
+		names[i] = s.name
+	}
+	values := stats.Downsample(points, names, ui.routineHist.dataWidth())
+
+	data := make([][]float64, 0, len(ui.histSeries))
+	colors := make([]termui.Color, 0, len(ui.histSeries))
+	legend := []legendLine{{prefix: fmt.Sprintf("Last %s", formatWindow(zoomLevels[ui.zoom]))}}
+	maxVal := 0.0
+	for i, s := range ui.histSeries {
+		color := seriesColors[i%len(seriesColors)]
+		key := " "
+		if i == ui.selectedSeries {
+			key = ">"
+		}
+		if s.hidden {
+			legend = append(legend, legendLine{prefix: key + " ", text: "- " + s.name, color: termui.ColorWhite})
+			continue
+		}
+		legend = append(legend, legendLine{prefix: key + " ", text: "■ " + s.name, color: color})
+		// Line chart needs at least two points
+		if len(values[s.name]) < 2 {
+			continue
+		}
+		data = append(data, values[s.name])
+		colors = append(colors, color)
+		maxVal = max(maxVal, slices.Max(values[s.name]))
+	}
+	ui.routineHist.Data = data
+	ui.routineHist.LineColors = colors
+	// Avoid division by zero if all values are zero
+	ui.routineHist.MaxVal = 0
+	if maxVal == 0 {
+		ui.routineHist.MaxVal = 1
+	}
+	ui.routineHist.buckets = 0
+	if len(data) > 0 {
+		ui.routineHist.buckets = len(data[0])
+		ui.routineHist.from = points[0].Time
+		ui.routineHist.to = points[len(points)-1].Time
+	}
+	ui.histLegend.lines = legend
+	ui.updateMetricPlot()
+}
+
+// updateMetricPlot plots the runtime metrics in the time span of the goroutine history. Heap size, thread and GC count
+// have different units, so each series is scaled from its minimum to its maximum in the span
+func (ui *UI) updateMetricPlot() {
+	if ui.metricPlot == nil {
+		return
+	}
+	window := zoomLevels[ui.zoom]
+	var points []stats.Point
+	if latest, ok := ui.history.Latest(); ok {
+		points = ui.metricHist.Since(latest.Time.Add(-window))
+	}
+	values := stats.Downsample(points, metricSeries, ui.metricPlot.dataWidth())
+
+	data := make([][]float64, 0, len(metricSeries))
+	colors := make([]termui.Color, 0, len(metricSeries))
+	legend := []legendLine{{prefix: "Min-max scaled"}}
+	for i, name := range metricSeries {
+		color := metricColors[i]
+		legend = append(legend, legendLine{text: "■ " + name, color: color})
+		known := slices.ContainsFunc(points, func(p stats.Point) bool {
+			_, ok := p.Values[name]
+			return ok
+		})
+		// Metric not provided by the source or no bucket fits into a narrow plot
+		if !known || len(values[name]) == 0 {
+			legend = append(legend, legendLine{prefix: "  -"})
+			continue
+		}
+		latest := points[len(points)-1].Values[name]
+		lo, hi := slices.Min(values[name]), slices.Max(values[name])
+		legend = append(legend, legendLine{prefix: fmt.Sprintf("  %.4g (%.4g-%.4g)", latest, lo, hi)})
+		// Line chart needs at least two points
+		if len(values[name]) < 2 {
+			continue
+		}
+		scaled := make([]float64, len(values[name]))
+		for j, v := range values[name] {
+			// Constant series are drawn in the middle
+			scaled[j] = 50
+			if hi > lo {
+				scaled[j] = (v - lo) / (hi - lo) * 100
+			}
+		}
+		data = append(data, scaled)
+		colors = append(colors, color)
+	}
+	ui.metricPlot.Title = fmt.Sprintf("Runtime metrics (last %s)", formatWindow(window))
+	ui.metricPlot.Data = data
+	ui.metricPlot.LineColors = colors
+	ui.metricPlot.MaxVal = 100
+	ui.metricPlot.buckets = 0
+	if len(data) > 0 {
+		ui.metricPlot.buckets = len(data[0])
+		ui.metricPlot.from = points[0].Time
+		ui.metricPlot.to = points[len(points)-1].Time
+	}
+	ui.metricLegend.lines = legend
+}
@@ -0,0 +1,71 @@
+package ui
+
+import (
+	"testing"
+	"time"
+
+	"github.com/becheran/roumon/internal/client"
+	"github.com/becheran/roumon/internal/model"
+	"github.com/becheran/roumon/internal/stats"
+	"github.com/stretchr/testify/assert"
+)
+
+func newHistoryUI() *UI {
+	ui := &UI{
+		history:     stats.NewHistory(100),
+		metricHist:  stats.NewHistory(100),
+		routineHist: newTimePlot(),
+		histLegend:  newSeriesLegend(),
+	}
+	ui.metricPlot, ui.metricLegend = newMetricPlot()
+	return ui
+}
+
+func TestMetricPlotTooNarrow(t *testing.T) {
+	ui := newHistoryUI()
+	ui.routineHist.SetRect(0, 0, 40, 10)
+	ui.metricPlot.SetRect(0, 0, 7, 10)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		now := start.Add(time.Duration(i) * time.Second)
+		ui.addHistory(now, []model.Goroutine{{Status: "running"}}, 1)
+		ui.addMetrics(now, client.Metrics{HeapInuse: 1 << 20, NumGC: uint32(i), Threads: 4})
+	}
+	assert.NotPanics(t, ui.updateHistPlot)
+
+	assert.Empty(t, ui.metricPlot.Data)
+	assert.Equal(t, []legendLine{
+		{prefix: "Min-max scaled"},
+		{text: "■ " + heapSeries, color: colorOrange},
+		{prefix: "  -"},
+		{text: "■ " + threadsSeries, color: colorPurple},
+		{prefix: "  -"},
+		{text: "■ " + gcSeries, color: colorSky},
+		{prefix: "  -"},
+	}, ui.metricLegend.lines)
+}
+
+func TestMetricPlotLegend(t *testing.T) {
+	ui := newHistoryUI()
+	ui.routineHist.SetRect(0, 0, 40, 10)
+	ui.metricPlot.SetRect(0, 0, 40, 10)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		now := start.Add(time.Duration(i) * time.Second)
+		ui.addHistory(now, []model.Goroutine{{Status: "running"}}, 1)
+		ui.addMetrics(now, client.Metrics{HeapInuse: uint64(i+1) << 20, NumGC: 7})
+	}
+	ui.updateHistPlot()
+
+	assert.Equal(t, []legendLine{
+		{prefix: "Min-max scaled"},
+		{text: "■ " + heapSeries, color: colorOrange},
+		{prefix: "  3 (1-3)"},
+		{text: "■ " + threadsSeries, color: colorPurple},
+		{prefix: "  -"},
+		{text: "■ " + gcSeries, color: colorSky},
+		{prefix: "  7 (7-7)"},
+	}, ui.metricLegend.lines)
+}
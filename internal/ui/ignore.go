@@ -0,0 +1,25 @@
+package ui
+
+import "fmt"
+
+// ignoreBadge returns a hint for the legend if goroutines are hidden by ignore rules
+func (ui *UI) ignoreBadge() string {
+	switch {
+	case ui.showIgnored:
+		return "[Showing ignored (Ctrl-U)](fg:yellow) | "
+	case ui.ignoredCount > 0:
+		return fmt.Sprintf("%d ignored (Ctrl-U) | ", ui.ignoredCount)
+	}
+	return ""
+}
+
+// toggleIgnored shows or hides the goroutines which match the ignore rules. The history keeps the counts of the previous setting
+func (ui *UI) toggleIgnored() {
+	ui.showIgnored = !ui.showIgnored
+	ui.setData(ui.scrape)
+	if ui.showIgnored {
+		ui.status.Text = "Showing ignored goroutines"
+	} else {
+		ui.status.Text = fmt.Sprintf("Hiding %d ignored goroutines", ui.ignoredCount)
+	}
+}
@@ -0,0 +1,162 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/becheran/roumon/internal/model"
+	"github.com/gizak/termui/v3/widgets"
+
+	termui "github.com/gizak/termui/v3"
+)
+
+// Value of goroutines without the grouped label key
+const noLabelValue = "(none)"
+
+// labelGroup contains all groups with the same value of the grouped label key
+type labelGroup struct {
+	value  string
+	count  int
+	groups []model.Group
+}
+
+// labelEntry is one row of the label browser. Either a key or a value of a key
+type labelEntry struct {
+	key   string
+	value string
+	isKey bool
+}
+
+func newLabelBrowser() *widgets.List {
+	browser := widgets.NewList()
+	browser.Title = "Labels (Enter: filter by value or group by key, Esc: close)"
+	browser.BorderStyle.Fg = termui.ColorCyan
+	browser.TextStyle = termui.NewStyle(termui.ColorWhite)
+	browser.SelectedRowStyle = termui.NewStyle(termui.ColorBlack, termui.ColorCyan)
+	browser.PaddingTop = padding
+	browser.PaddingRight = padding
+	browser.PaddingLeft = padding
+	browser.PaddingBottom = padding
+	return browser
+}
+
+// labelKeys returns the sorted label keys of the latest snapshot
+func (ui *UI) labelKeys() []string {
+	counts := model.LabelCounts(ui.groups)
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// updateLabelBrowser lists all label keys and their values with goroutine counts
+func (ui *UI) updateLabelBrowser() {
+	counts := model.LabelCounts(ui.groups)
+	ui.labelEntries = ui.labelEntries[:0]
+	ui.labelBrowser.Rows = ui.labelBrowser.Rows[:0]
+	for _, k := range ui.labelKeys() {
+		total := 0
+		for _, c := range counts[k] {
+			total += c.Count
+		}
+		ui.labelEntries = append(ui.labelEntries, labelEntry{key: k, isKey: true})
+		ui.labelBrowser.Rows = append(ui.labelBrowser.Rows, fmt.Sprintf("[%s](fg:cyan) (%d)", escape(k), total))
+		for _, c := range counts[k] {
+			ui.labelEntries = append(ui.labelEntries, labelEntry{key: k, value: c.Value})
+			ui.labelBrowser.Rows = append(ui.labelBrowser.Rows, fmt.Sprintf("  %s (%d)", escape(c.Value), c.Count))
+		}
+	}
+	if len(ui.labelEntries) == 0 {
+		ui.labelBrowser.Rows = append(ui.labelBrowser.Rows, "No labels. Labels are only available with -format=grouped or -format=proto")
+	}
+	ui.labelBrowser.SelectedRow = min(ui.labelBrowser.SelectedRow, max(len(ui.labelBrowser.Rows)-1, 0))
+}
+
+// handleLabelKey handles the keys of the label browser until a label is chosen or the browser is closed
+func (ui *UI) handleLabelKey(keyID string) {
+	switch keyID {
+	case "<Escape>", "<C-k>":
+		ui.overlay = overlayNone
+	case "<Enter>":
+		if ui.labelBrowser.SelectedRow < len(ui.labelEntries) {
+			ui.applyLabelEntry(ui.labelEntries[ui.labelBrowser.SelectedRow])
+		}
+		ui.overlay = overlayNone
+	default:
+		scrollList(ui.labelBrowser, keyID)
+	}
+}
+
+// applyLabelEntry groups the list by the key or filters by the value of the entry
+func (ui *UI) applyLabelEntry(entry labelEntry) {
+	if entry.isKey {
+		ui.setGrouping(entry.key)
+		return
+	}
+	ui.filter.Text = model.LabelFilter{Key: entry.key, Value: entry.value}.String()
+	ui.filtered = true
+	ui.list.SelectedRow = 0
+	ui.updateList()
+}
+
+// groupByLabel groups the groups by the value of the label key. Largest groups first
+func groupByLabel(groups []model.Group, key string) []labelGroup {
+	var result []labelGroup
+	index := make(map[string]int)
+	for _, g := range groups {
+		value, ok := g.Labels[key]
+		if !ok {
+			value = noLabelValue
+		}
+		i, ok := index[value]
+		if !ok {
+			i = len(result)
+			index[value] = i
+			result = append(result, labelGroup{value: value})
+		}
+		result[i].count += g.Count
+		result[i].groups = append(result[i].groups, g)
+	}
+	slices.SortStableFunc(result, func(a, b labelGroup) int {
+		return b.count - a.count
+	})
+	return result
+}
+
+// updateLabelGroupList shows the goroutines grouped by the value of the selected label key
+func (ui *UI) updateLabelGroupList() {
+	labelGroups := groupByLabel(ui.filteredGroups, ui.groupLabel)
+
+	ui.list.Rows = make([]string, len(labelGroups))
+	for i, lg := range labelGroups {
+		ui.list.Rows[i] = fmt.Sprintf("[%5d x](fg:green) %s=%s", lg.count, escape(ui.groupLabel), escape(lg.value))
+	}
+
+	if len(labelGroups) == 0 {
+		ui.list.SelectedRow = 0
+		ui.details.Text = ""
+		ui.list.Title = fmt.Sprintf("Labels (0/0) by %s", ui.groupLabel)
+		return
+	}
+	ui.list.SelectedRow = min(max(ui.list.SelectedRow, 0), len(labelGroups)-1)
+
+	lg := labelGroups[ui.list.SelectedRow]
+	var sb strings.Builder
+	for _, g := range lg.groups {
+		name := "(no frames)"
+		if frame, ok := ui.groupFrame(g); ok {
+			name = escape(frame.ShortName())
+		}
+		sb.WriteString(fmt.Sprintf("  %5d x %s\n", g.Count, name))
+	}
+	ui.details.Text = fmt.Sprintf("Label: [%s=%s](mod:bold)\n\nGoroutines: [%d](mod:bold)\n\nStacks:\n%s",
+		ui.groupLabel,
+		lg.value,
+		lg.count,
+		sb.String())
+
+	ui.list.Title = fmt.Sprintf("Labels (%d/%d) by %s", ui.list.SelectedRow+1, len(ui.list.Rows), ui.groupLabel)
+}
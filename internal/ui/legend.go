@@ -0,0 +1,55 @@
+package ui
+
+import (
+	"image"
+
+	termui "github.com/gizak/termui/v3"
+)
+
+// Additional 256 colors of the plot series. The termui style markup only knows the basic colors
+const (
+	colorOrange = termui.Color(208)
+	colorPurple = termui.Color(141)
+	colorSky    = termui.Color(39)
+)
+
+// seriesLegend lists the series of a plot in their line colors
+type seriesLegend struct {
+	*termui.Block
+	lines []legendLine
+}
+
+// legendLine is one line of a legend. The text is drawn in the color after the uncolored prefix
+type legendLine struct {
+	prefix string
+	text   string
+	color  termui.Color
+}
+
+func newSeriesLegend() *seriesLegend {
+	legend := &seriesLegend{Block: termui.NewBlock()}
+	legend.BorderLeft = false
+	legend.PaddingTop = padding
+	legend.PaddingRight = padding
+	legend.PaddingBottom = padding
+	return legend
+}
+
+// Draw the lines cut to the width of the legend
+func (l *seriesLegend) Draw(buf *termui.Buffer) {
+	l.Block.Draw(buf)
+	if l.Inner.Dx() <= 0 {
+		return
+	}
+	for i, line := range l.lines {
+		y := l.Inner.Min.Y + i
+		if y >= l.Inner.Max.Y {
+			return
+		}
+		cells := append(termui.RunesToStyledCells([]rune(line.prefix), termui.Theme.Paragraph.Text),
+			termui.RunesToStyledCells([]rune(line.text), termui.NewStyle(line.color))...)
+		for _, c := range termui.BuildCellWithXArray(termui.TrimCells(cells, l.Inner.Dx())) {
+			buf.SetCell(c.Cell, image.Pt(l.Inner.Min.X+c.X, y))
+		}
+	}
+}
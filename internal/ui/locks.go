@@ -0,0 +1,70 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/becheran/roumon/internal/analysis"
+	"github.com/gizak/termui/v3/widgets"
+
+	termui "github.com/gizak/termui/v3"
+)
+
+// Maximum number of waiter IDs per row of the hot locks and channel waits views
+const maxWaiterIDs = 10
+
+func newLockView() *widgets.List {
+	view := widgets.NewList()
+	view.Title = "Hot locks"
+	view.BorderStyle.Fg = termui.ColorRed
+	view.TextStyle = termui.NewStyle(termui.ColorWhite)
+	view.SelectedRowStyle = termui.NewStyle(termui.ColorBlack, termui.ColorRed)
+	view.PaddingTop = padding
+	view.PaddingRight = padding
+	view.PaddingLeft = padding
+	view.PaddingBottom = padding
+	return view
+}
+
+// updateLockView shows the locks with the most waiting goroutines as a table
+func (ui *UI) updateLockView() {
+	ui.lockView.Rows = ui.lockView.Rows[:0]
+	if ui.groupedFormat {
+		ui.lockView.Title = "Hot locks"
+		ui.lockView.Rows = append(ui.lockView.Rows, "Locks can only be shown with -format=stacks")
+		return
+	}
+
+	locks := analysis.HotLocks(ui.origData)
+	waiting := 0
+	for _, l := range locks {
+		waiting += len(l.Waiters)
+	}
+	ui.lockView.Title = fmt.Sprintf("Hot locks (%d locks, %d waiting goroutines, Esc: close)", len(locks), waiting)
+	ui.lockView.Rows = append(ui.lockView.Rows, fmt.Sprintf("[%7s  %-12s  %-28s  %s](mod:bold)", "Waiters", "Longest wait", "Lock", "Call site"))
+	for _, l := range locks {
+		site := "(unknown)"
+		if l.Site.FuncName != "" {
+			site = fmt.Sprintf("%s %s", escape(l.Site.ShortName()), l.Site.Location())
+		}
+		if l.Sites > 1 {
+			site += fmt.Sprintf(" [+%d sites](fg:yellow)", l.Sites-1)
+		}
+		wait := formatWait(l.LongestWait)
+		if wait == "" {
+			wait = "<1m"
+		}
+		ui.lockView.Rows = append(ui.lockView.Rows, fmt.Sprintf("[%7d](fg:red)  %-12s  %-28s  %s", len(l.Waiters), wait, escape(l.Name()), site))
+
+		ui.lockView.Rows = append(ui.lockView.Rows, fmt.Sprintf("%9s goroutines %s", "", analysis.JoinIDs(l.Waiters, maxWaiterIDs)))
+	}
+	if len(locks) == 0 {
+		ui.lockView.Rows = append(ui.lockView.Rows, "No goroutines wait for a mutex")
+	}
+	for _, l := range locks {
+		if l.Address == 0 {
+			ui.lockView.Rows = append(ui.lockView.Rows, "", "[Waiters of locks with unknown address are grouped by call site. RLock waiters never show the address in the dump](fg:yellow)")
+			break
+		}
+	}
+	ui.lockView.SelectedRow = min(ui.lockView.SelectedRow, len(ui.lockView.Rows)-1)
+}
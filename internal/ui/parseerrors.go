@@ -0,0 +1,52 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gizak/termui/v3/widgets"
+
+	termui "github.com/gizak/termui/v3"
+)
+
+func newParseErrorView() *widgets.Paragraph {
+	view := widgets.NewParagraph()
+	view.Title = "Parse errors"
+	view.TextStyle.Fg = termui.ColorWhite
+	view.BorderStyle.Fg = termui.ColorYellow
+	view.PaddingTop = padding
+	view.PaddingRight = padding
+	view.PaddingLeft = padding
+	view.PaddingBottom = padding
+	return view
+}
+
+// parseErrorBadge returns a warning for the legend if lines of the latest dump could not be parsed
+func (ui *UI) parseErrorBadge() string {
+	if len(ui.parseErrors) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("[! %d unparsed lines (F9)](fg:black,bg:yellow) | ", len(ui.parseErrors))
+}
+
+// updateParseErrorView lists the unparsed lines with line number and reason
+func (ui *UI) updateParseErrorView() {
+	if len(ui.parseErrors) == 0 {
+		ui.parseErrorView.Text = "All lines of the latest goroutine dump were parsed\n\nPress any key to continue"
+		return
+	}
+
+	// Two lines per error plus border, padding and footer
+	maxErrors := max((ui.parseErrorView.Dy()-6)/2, 1)
+	var sb strings.Builder
+	for i, e := range ui.parseErrors {
+		if i >= maxErrors {
+			sb.WriteString(fmt.Sprintf("... and %d more\n", len(ui.parseErrors)-maxErrors))
+			break
+		}
+		sb.WriteString(fmt.Sprintf("[%s](fg:yellow)\n", escape(e.Error())))
+		sb.WriteString(fmt.Sprintf("  %5d | %s\n", e.Line, escape(strings.ReplaceAll(e.Text, "\t", "    "))))
+	}
+	sb.WriteString("\nPress any key to continue")
+	ui.parseErrorView.Text = sb.String()
+}
@@ -0,0 +1,265 @@
+package ui
+
+import (
+	"fmt"
+	"image"
+	"sync"
+	"time"
+
+	"github.com/becheran/roumon/internal/analysis"
+	"github.com/becheran/roumon/internal/client"
+	"github.com/gizak/termui/v3/widgets"
+
+	termui "github.com/gizak/termui/v3"
+)
+
+// Name of the first tab which shows the details of the selected goroutine
+const tabGoroutines = "goroutines"
+
+func newTabs(profiles []string) *widgets.TabPane {
+	tabs := widgets.NewTabPane(append([]string{tabGoroutines}, profiles...)...)
+	tabs.Title = "Tab: switch, Right/Left: focus"
+	tabs.BorderStyle.Fg = termui.ColorCyan
+	tabs.ActiveTabStyle = termui.NewStyle(termui.ColorBlack, termui.ColorCyan)
+	tabs.InactiveTabStyle = termui.NewStyle(termui.ColorWhite)
+	tabs.PaddingLeft = padding
+	return tabs
+}
+
+func newProfileView() *widgets.List {
+	view := widgets.NewList()
+	view.BorderStyle.Fg = termui.ColorCyan
+	view.TextStyle = termui.NewStyle(termui.ColorWhite)
+	view.SelectedRowStyle = termui.NewStyle(termui.ColorBlack, termui.ColorCyan)
+	view.PaddingTop = padding
+	view.PaddingRight = padding
+	view.PaddingLeft = padding
+	view.PaddingBottom = padding
+	return view
+}
+
+// detailsPane shows the goroutine details or the selected runtime profile in the same cell of the grid
+type detailsPane struct {
+	sync.Mutex
+	ui *UI
+}
+
+func (p *detailsPane) GetRect() image.Rectangle {
+	return p.ui.details.GetRect()
+}
+
+func (p *detailsPane) SetRect(x1, y1, x2, y2 int) {
+	p.ui.details.SetRect(x1, y1, x2, y2)
+	p.ui.profileView.SetRect(x1, y1, x2, y2)
+}
+
+func (p *detailsPane) Draw(buf *termui.Buffer) {
+	if p.ui.profileTab() != "" {
+		p.ui.profileView.Draw(buf)
+		return
+	}
+	p.ui.details.Draw(buf)
+}
+
+// profileTab returns the name of the selected runtime profile. Empty if the goroutine details are shown
+func (ui *UI) profileTab() string {
+	if ui.tabs == nil || ui.tabs.ActiveTabIndex == 0 {
+		return ""
+	}
+	return ui.opts.Profiles[ui.tabs.ActiveTabIndex-1]
+}
+
+// nextTab switches to the next tab
+func (ui *UI) nextTab() {
+	if ui.tabs == nil {
+		ui.status.Text = "No runtime profiles are fetched. Start with -profiles=block,mutex,threadcreate"
+		return
+	}
+	ui.tabs.ActiveTabIndex = (ui.tabs.ActiveTabIndex + 1) % len(ui.tabs.TabNames)
+	ui.profileView.SelectedRow = 0
+	ui.focusProfile(ui.profileFocus)
+	ui.updateProfileView()
+}
+
+// focusProfile moves the focus of the arrow keys to the runtime profile or back to the goroutine list.
+// The goroutine list keeps the focus while the goroutine details are shown
+func (ui *UI) focusProfile(focus bool) {
+	ui.profileFocus = focus && ui.profileTab() != ""
+	ui.profileView.BorderStyle.Fg = termui.ColorCyan
+	if ui.profileFocus {
+		ui.profileView.BorderStyle.Fg = termui.ColorYellow
+	}
+}
+
+// scrollProfile scrolls the selected runtime profile. Returns false if the goroutine list has the focus
+func (ui *UI) scrollProfile(keyID string) bool {
+	if !ui.profileFocus || ui.profileTab() == "" {
+		return false
+	}
+	switch keyID {
+	case "<Down>":
+		ui.profileView.ScrollDown()
+	case "<Up>":
+		ui.profileView.ScrollUp()
+	case "<PageDown>":
+		ui.profileView.ScrollPageDown()
+	case "<PageUp>":
+		ui.profileView.ScrollPageUp()
+	case "<Home>":
+		ui.profileView.ScrollTop()
+	case "<End>":
+		ui.profileView.ScrollBottom()
+	default:
+		return false
+	}
+	return true
+}
+
+// setProfiles keeps the latest and the previous runtime profiles. Failed requests keep the last profile and show the error
+func (ui *UI) setProfiles(profiles []client.ProfileScrape) {
+	for _, p := range profiles {
+		last := ui.profiles[p.Name]
+		if p.Err != nil {
+			last.Err = p.Err
+			ui.profiles[p.Name] = last
+			continue
+		}
+		ui.prevProfiles[p.Name] = last.Profile
+		ui.profiles[p.Name] = p
+	}
+}
+
+// formatDelay returns a rounded duration of the nanoseconds in the block and mutex profiles
+func formatDelay(ns int64) string {
+	d := time.Duration(ns)
+	switch {
+	case d >= time.Second:
+		return d.Round(time.Millisecond).String()
+	case d >= time.Millisecond:
+		return d.Round(time.Microsecond).String()
+	}
+	return d.String()
+}
+
+// profileColumn is a value column of the profile table
+type profileColumn struct {
+	header     string
+	sampleType string
+	format     func(int64) string
+}
+
+func formatCount(v int64) string {
+	return fmt.Sprintf("%d", v)
+}
+
+// Value columns of the profiles. The first column is the sort order
+var profileColumns = map[string][]profileColumn{
+	client.ProfileBlock:        {{"Delay", "delay", formatDelay}, {"Contentions", "contentions", formatCount}},
+	client.ProfileMutex:        {{"Delay", "delay", formatDelay}, {"Contentions", "contentions", formatCount}},
+	client.ProfileThreadCreate: {{"Threads", "threadcreate", formatCount}},
+}
+
+// Shown if a profile has no samples
+var emptyProfileHints = map[string]string{
+	client.ProfileBlock:        "No samples. The block profile is only recorded after runtime.SetBlockProfileRate",
+	client.ProfileMutex:        "No samples. The mutex profile is only recorded after runtime.SetMutexProfileFraction",
+	client.ProfileThreadCreate: "No samples",
+}
+
+// updateProfileView shows the top call sites of the selected runtime profile as a table
+func (ui *UI) updateProfileView() {
+	name := ui.profileTab()
+	if name == "" {
+		return
+	}
+	view := ui.profileView
+	view.Rows = view.Rows[:0]
+	scrape, ok := ui.profiles[name]
+	view.Title = fmt.Sprintf("%s profile", name)
+	if scrape.Profile != nil {
+		view.Title = fmt.Sprintf("%s profile (since process start, fetched %s)", name, scrape.Time.Format(time.TimeOnly))
+	}
+	if scrape.Err != nil {
+		view.Rows = append(view.Rows, fmt.Sprintf("[%s](fg:red)", escape(scrape.Err.Error())))
+	}
+	if !ok || scrape.Profile == nil {
+		if scrape.Err == nil {
+			view.Rows = append(view.Rows, "Waiting for the first fetch")
+		}
+		return
+	}
+
+	columns := profileColumns[name]
+	indices := make([]int, len(columns))
+	for i, c := range columns {
+		indices[i] = scrape.Profile.ValueIndex(c.sampleType)
+	}
+	sites := analysis.TopSites(scrape.Profile, indices[0])
+	if len(sites) == 0 {
+		view.Rows = append(view.Rows, emptyProfileHints[name])
+		return
+	}
+
+	// Increase of the sort value since the previous fetch
+	prev := ui.prevProfiles[name]
+	previous := make(map[string]int64)
+	if prev != nil {
+		for _, s := range analysis.TopSites(prev, prev.ValueIndex(columns[0].sampleType)) {
+			if indices[0] >= 0 && indices[0] < len(s.Values) {
+				previous[s.Key()] = s.Values[indices[0]]
+			}
+		}
+	}
+	// Goroutines of the latest dump blocked in the same function. Only known for single goroutines
+	var blocked map[string]int
+	showBlocked := name != client.ProfileThreadCreate && !ui.groupedFormat
+	if showBlocked {
+		blocked = analysis.BlockedIn(ui.origData)
+	}
+
+	header := ""
+	for _, c := range columns {
+		header += fmt.Sprintf("%12s  ", c.header)
+	}
+	header += fmt.Sprintf("%12s  ", "Recent")
+	if showBlocked {
+		header += fmt.Sprintf("%11s  ", "Blocked now")
+	}
+	site := "Call site"
+	if name == client.ProfileMutex {
+		site = "Call site (lock holder)"
+	}
+	view.Rows = append(view.Rows, fmt.Sprintf("[%s%s](mod:bold)", header, site))
+
+	for _, s := range sites {
+		row := ""
+		for i, c := range columns {
+			value := "-"
+			if indices[i] >= 0 && indices[i] < len(s.Values) {
+				value = c.format(s.Values[indices[i]])
+			}
+			row += fmt.Sprintf("%12s  ", value)
+		}
+		recent := "-"
+		if prev != nil && indices[0] >= 0 && indices[0] < len(s.Values) {
+			if diff := s.Values[indices[0]] - previous[s.Key()]; diff > 0 {
+				recent = "+" + columns[0].format(diff)
+			}
+		}
+		row += fmt.Sprintf("%12s  ", recent)
+		if showBlocked {
+			if n := blocked[s.Frame.FuncName]; n > 0 {
+				row += fmt.Sprintf("[%11d](fg:red)  ", n)
+			} else {
+				row += fmt.Sprintf("%11s  ", "-")
+			}
+		}
+		if s.Frame.FuncName == "" {
+			row += "(no stack)"
+		} else {
+			row += fmt.Sprintf("%s %s", escape(s.Frame.ShortName()), s.Frame.Location())
+		}
+		view.Rows = append(view.Rows, row)
+	}
+	view.SelectedRow = min(view.SelectedRow, len(view.Rows)-1)
+}
@@ -0,0 +1,66 @@
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/becheran/roumon/internal/model"
+)
+
+// Snapshot file formats
+const (
+	SnapshotText = "text" // Original pprof debug=2 text format
+	SnapshotJSON = "json"
+)
+
+// saveSnapshot writes the routines to a timestamped file in dir and returns the path of the file
+func saveSnapshot(dir, format string, routines []model.Goroutine) (path string, err error) {
+	return writeSnapshot(dir, format, routines, func(w io.Writer) error {
+		return model.WriteStackFrame(w, routines)
+	})
+}
+
+// saveGroupSnapshot writes the groups in the pprof debug=1 format or as JSON to a timestamped file in dir
+func saveGroupSnapshot(dir, format string, groups []model.Group) (path string, err error) {
+	return writeSnapshot(dir, format, groups, func(w io.Writer) error {
+		return model.WriteGroups(w, groups)
+	})
+}
+
+// writeSnapshot writes data as JSON or with writeText to a timestamped file in dir
+func writeSnapshot(dir, format string, data any, writeText func(io.Writer) error) (path string, err error) {
+	ext := "txt"
+	if format == SnapshotJSON {
+		ext = "json"
+	}
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create snapshot dir. Err: %s", err.Error())
+	}
+	path = filepath.Join(dir, fmt.Sprintf("roumon-%s.%s", time.Now().Format("20060102-150405.000"), ext))
+
+	f, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to create snapshot file. Err: %s", err.Error())
+	}
+	defer func() {
+		if closeErr := f.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("failed to close snapshot file. Err: %s", closeErr.Error())
+		}
+	}()
+
+	if format == SnapshotJSON {
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(data)
+	} else {
+		err = writeText(f)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to write snapshot. Err: %s", err.Error())
+	}
+	return path, nil
+}
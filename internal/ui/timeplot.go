@@ -0,0 +1,62 @@
+package ui
+
+import (
+	"image"
+	"time"
+
+	"github.com/gizak/termui/v3/widgets"
+
+	termui "github.com/gizak/termui/v3"
+)
+
+const (
+	// Same as the unexported termui plot constants
+	plotYAxisLabelsWidth = 4
+	plotXLabelGap        = 4
+)
+
+// timePlot is a line plot which labels the x-axis with wall-clock times instead of data indices
+type timePlot struct {
+	*widgets.Plot
+	from    time.Time // Time of the first data point
+	to      time.Time // Time of the last data point
+	buckets int       // Number of data points per series
+}
+
+func newTimePlot() *timePlot {
+	plot := widgets.NewPlot()
+	plot.HorizontalScale = 1
+	return &timePlot{Plot: plot}
+}
+
+// dataWidth returns the number of data points which fit into the plot
+func (p *timePlot) dataWidth() int {
+	return p.Inner.Dx() - plotYAxisLabelsWidth - 1
+}
+
+// Draw the plot and replace the x-axis labels with times
+func (p *timePlot) Draw(buf *termui.Buffer) {
+	p.Plot.Draw(buf)
+	if !p.ShowAxes {
+		return
+	}
+
+	y := p.Inner.Max.Y - 1
+	for x := p.Inner.Min.X; x < p.Inner.Max.X; x++ {
+		buf.SetCell(termui.NewCell(' '), image.Pt(x, y))
+	}
+	if p.buckets < 2 {
+		return
+	}
+
+	layout := "15:04:05"
+	if p.to.Sub(p.from) > 30*time.Minute {
+		layout = "15:04"
+	}
+	minX := p.Inner.Min.X + plotYAxisLabelsWidth + 1
+	step := p.to.Sub(p.from) / time.Duration(p.buckets-1)
+	for j := 0; j < p.buckets && minX+j+len(layout) <= p.Inner.Max.X; j += len(layout) + plotXLabelGap {
+		label := p.from.Add(step * time.Duration(j)).Format(layout)
+		buf.SetString(label, termui.NewStyle(p.AxesColor), image.Pt(minX+j, y))
+	}
+}
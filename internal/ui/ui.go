@@ -1,21 +1,48 @@
 package ui
 
 import (
+	"encoding/json"
 	"fmt"
 	"log"
 	"slices"
-	"sort"
 	"strings"
+	"time"
 
+	"github.com/becheran/roumon/internal/analysis"
+	"github.com/becheran/roumon/internal/baseline"
+	"github.com/becheran/roumon/internal/client"
 	"github.com/becheran/roumon/internal/model"
+	"github.com/becheran/roumon/internal/stats"
 	"github.com/gizak/termui/v3/widgets"
 
 	termui "github.com/gizak/termui/v3"
 )
 
 const (
-	padding         = 1
-	keepRoutineHist = 100
+	padding = 1
+)
+
+// styleEscaper replaces the brackets of the termui style markup, which has no escape sequence, with similar characters
+var styleEscaper = strings.NewReplacer("[", "⟦", "]", "⟧")
+
+// escape returns the text with the brackets of the style markup replaced, so text such as generic function names
+// or raw dump lines is shown as is
+func escape(text string) string {
+	return styleEscaper.Replace(text)
+}
+
+// overlay is a view shown on top of the main screen until it is closed. Keys are routed to the open view
+// while scrapes are still applied
+type overlay int
+
+// Overlay views
+const (
+	overlayNone overlay = iota
+	overlayParseErrors
+	overlayLabels
+	overlayDeadlocks
+	overlayLocks
+	overlayChannels
 )
 
 // UI contains all user interface elements
@@ -23,24 +50,91 @@ type UI struct {
 	list           *widgets.List
 	filter         *widgets.Paragraph
 	details        *widgets.Paragraph
-	routineHist    *widgets.Plot
+	routineHist    *timePlot
+	histLegend     *seriesLegend
+	metricPlot     *timePlot // Runtime metrics. Nil if no metrics are fetched
+	metricLegend   *seriesLegend
 	barchart       *widgets.BarChart
 	barchartLegend *widgets.Paragraph
-	paused         *widgets.Paragraph
 	legend         *widgets.Paragraph
 	help           *widgets.Paragraph
+	status         *widgets.Paragraph
+	connBar        *widgets.Paragraph
+	parseErrorView *widgets.Paragraph
+	labelBrowser   *widgets.List
+	deadlockView   *widgets.Paragraph
+	lockView       *widgets.List
+	channelView    *widgets.List
+	tabs           *widgets.TabPane // Goroutine details and runtime profiles. Nil if no profiles are fetched
+	profileView    *widgets.List
+	profileFocus   bool    // Arrow keys scroll the runtime profile instead of the goroutine list
+	overlay        overlay // Open view on top of the main screen
+
+	clipboard      *clipboard
+	conn           connection
+	parseErrors    []*model.ParseError // Skipped lines of the latest dump
+	opts           Options
+	grid           *termui.Grid
+	history        *stats.History
+	metricHist     *stats.History
+	histSeries     []histSeries
+	zoom           int // Index of zoomLevels
+	selectedSeries int
+	filtered       bool
+	order          int // Order of the routine list
+	showWaitHist   bool
+	hideStdlib     bool
+	modules        model.Classifier // Configured own modules
+	classifier     model.Classifier // Own modules configured or detected in the latest snapshot
+	pathMode       model.PathMode
+	moduleRoots    []string // Detected roots of own modules for path shortening
+	paused         bool
+	pending        client.Scrape // Latest snapshot received while paused
+	scrape         client.Scrape // Displayed snapshot including ignored goroutines
+	ignore         model.IgnoreRules
+	showIgnored    bool
+	ignoredCount   int // Ignored goroutines of the displayed snapshot
+	deadlocks      []analysis.Finding
+	profiles       map[string]client.ProfileScrape // Latest runtime profiles by name
+	prevProfiles   map[string]*model.Profile       // Runtime profiles of the fetch before the latest one
+	pendingCount   int
+	width          int
+	height         int
+	origData       []model.Goroutine
+	filteredData   []model.Goroutine
+	groups         []model.Group // Identical stacks of the latest snapshot
+	filteredGroups []model.Group
+	groupView      bool   // Show groups of identical stacks instead of goroutines
+	groupLabel     string // Label key to group by. Empty to group by identical stacks
+	labelEntries   []labelEntry
+	baseline       *baseline.Baseline            // Expected stack groups. Nil if not set
+	violations     []baseline.Violation          // Stack groups of the latest snapshot not covered by the baseline
+	uncovered      map[string]baseline.Violation // Violations by stack fingerprint
+	groupedFormat  bool                          // Profile format has no single goroutines
+	stats          *stats.Stats
+	statsWindows   []time.Duration
+	statsWindow    int // Index of selected window in statsWindows
+}
 
-	grid          *termui.Grid
-	filtered      bool
-	origData      []model.Goroutine
-	filteredData  []model.Goroutine
-	minGoRoutines int
-	maxGoRoutines int
-	avgGoRoutines float64
+// Options for the console user interface
+type Options struct {
+	ClipboardMode  string             // One of ClipboardAuto, ClipboardOSC52 or ClipboardFile
+	ClipboardFile  string             // Used if OSC 52 is not available
+	SnapshotDir    string             // Directory for saved snapshots
+	SnapshotFormat string             // One of SnapshotText or SnapshotJSON
+	StatsWindow    time.Duration      // Initial time window for the statistics
+	HistoryLength  time.Duration      // Time span of the kept goroutine history
+	ScrapeInterval time.Duration      // Interval of the client between two scrapes
+	Modules        string             // Comma separated import path prefixes of own code. Empty for automatic detection
+	Baseline       *baseline.Baseline // Highlight stacks not covered by the baseline if set
+	Ignore         model.IgnoreRules  // Goroutines to hide from the list and statistics
+	DeadlockAfter  time.Duration      // Minimum wait time of blocked goroutines for deadlock detection
+	Profiles       []string           // Runtime profiles fetched by the client. Shown as tabs next to the goroutine details
+	Metrics        string             // Source of runtime metrics fetched by the client. Plotted next to the goroutine history if set
 }
 
 // NewUI creates a new console user interface
-func NewUI() *UI {
+func NewUI(opts Options) *UI {
 	if err := termui.Init(); err != nil {
 		log.Fatalf("Failed to initialize termui: %v", err)
 	}
@@ -55,16 +149,15 @@ func NewUI() *UI {
 	filter.PaddingLeft = padding
 	filter.PaddingBottom = padding
 
-	plot := widgets.NewPlot()
-	plot.Data = make([][]float64, 1)
-	plot.Data[0] = make([]float64, 2, keepRoutineHist)
+	plot := newTimePlot()
 	plot.AxesColor = termui.ColorWhite
-	plot.LineColors[0] = termui.ColorGreen
-	plot.HorizontalScale = 2
 	plot.PaddingTop = padding
 	plot.PaddingRight = padding
 	plot.PaddingLeft = padding
 	plot.PaddingBottom = padding
+	plot.BorderRight = false
+
+	histLegend := newSeriesLegend()
 
 	routineList := widgets.NewList()
 	routineList.PaddingTop = padding
@@ -81,13 +174,13 @@ func NewUI() *UI {
 	details.PaddingRight = padding
 	details.PaddingLeft = padding
 	details.PaddingBottom = padding
-	details.Title = "Details"
+	details.Title = "Details (full paths)"
 	details.TextStyle = termui.NewStyle(termui.ColorWhite)
 	details.SetRect(0, 0, 60, 10)
 
 	barchart := widgets.NewBarChart()
 	barchart.Title = "Status"
-	barchart.BarWidth = 3
+	barchart.BarWidth = 4
 	barchart.BarGap = 1
 	barchart.BarColors = []termui.Color{termui.ColorGreen}
 	barchart.NumStyles = []termui.Style{termui.NewStyle(termui.ColorBlack)}
@@ -108,105 +201,254 @@ func NewUI() *UI {
 
 	help := widgets.NewParagraph()
 	help.TextStyle.Fg = termui.ColorGreen
-	help.Text = "Help\n\nArrows up/down: Select from list\nText input: Filter results\nF10: Quit\nF2: Pause\n\nPress any key to continue"
+	help.Text = "Help\n\nArrows up/down: Select from list\nText input: Filter results\nF10: Quit\nF2: Pause/Resume updates\nF3: Cycle statistics window\nF4: Reset statistics\nF5: Select history series\nF6: Show/Hide history series\nF7/F8: Zoom history in/out\nF9: Show unparsed lines\nCtrl-Y: Copy stack\nCtrl-J: Copy as JSON\nCtrl-L: Copy top frame location\nCtrl-S: Save snapshot\nCtrl-W: Save filtered snapshot\nCtrl-O: Cycle list order\nCtrl-B: Status/Wait time chart\nCtrl-T: Hide/Show stdlib frames\nCtrl-P: Cycle path display\nCtrl-G: Cycle grouping (stacks, labels)\nCtrl-K: Browse labels\nCtrl-E: Save baseline\nCtrl-U: Show/Hide ignored goroutines\nCtrl-D: Show possible deadlocks\nCtrl-X: Show hot locks\nCtrl-N: Show channel waits\nTab: Switch goroutine details and runtime profiles\nRight/Left: Scroll runtime profile/goroutine list\n\nPress any key to continue"
 	help.PaddingBottom = 2
 	help.PaddingLeft = 2
 	help.PaddingRight = 2
 	help.PaddingTop = 2
 
-	paused := widgets.NewParagraph()
-	paused.TextStyle.Fg = termui.ColorGreen
-	paused.Text = "Paused. Press any key to continue"
-	paused.PaddingBottom = 2
-	paused.PaddingLeft = 2
-	paused.PaddingRight = 2
-	paused.PaddingTop = 2
-
 	legend := widgets.NewParagraph()
-	legend.Text = "F1 Help | F2 Pause | F10 Quit"
 	legend.TextStyle.Fg = termui.ColorGreen
 	legend.Border = false
 
+	status := widgets.NewParagraph()
+	status.TextStyle.Fg = termui.ColorYellow
+	status.Border = false
+
+	connBar := widgets.NewParagraph()
+	connBar.Border = false
+	connBar.TextStyle.Fg = termui.ColorWhite
+
 	grid := termui.NewGrid()
 
+	statsWindows := []time.Duration{time.Minute, 5 * time.Minute, time.Hour}
+	if opts.StatsWindow > 0 && !slices.Contains(statsWindows, opts.StatsWindow) {
+		statsWindows = append(statsWindows, opts.StatsWindow)
+		slices.Sort(statsWindows)
+	}
+
+	scrapeInterval := opts.ScrapeInterval
+	if scrapeInterval <= 0 {
+		scrapeInterval = client.DefaultInterval
+	}
+
 	ui := UI{
 		filter:         filter,
 		list:           routineList,
 		details:        details,
 		routineHist:    plot,
+		histLegend:     histLegend,
 		barchart:       barchart,
 		barchartLegend: barchartLabel,
 		help:           help,
-		paused:         paused,
 		legend:         legend,
-		grid:           grid,
+		status:         status,
+		connBar:        connBar,
+		parseErrorView: newParseErrorView(),
+		labelBrowser:   newLabelBrowser(),
+		deadlockView:   newDeadlockView(),
+		lockView:       newLockView(),
+		channelView:    newChannelView(),
+		profileView:    newProfileView(),
+		profiles:       make(map[string]client.ProfileScrape),
+		prevProfiles:   make(map[string]*model.Profile),
+		clipboard:      newClipboard(opts.ClipboardMode, opts.ClipboardFile),
+		opts:           opts,
+		modules:        model.NewClassifier(opts.Modules),
+		baseline:       opts.Baseline,
+		ignore:         opts.Ignore,
+		stats:          stats.New(statsWindows[len(statsWindows)-1]),
+		statsWindows:   statsWindows,
+		statsWindow:    max(slices.Index(statsWindows, opts.StatsWindow), 0),
+		// One point per scrape
+		history:    stats.NewHistory(int(opts.HistoryLength / scrapeInterval)),
+		metricHist: stats.NewHistory(int(opts.HistoryLength / scrapeInterval)),
+		zoom:       1,
+		grid:       grid,
 	}
 
+	// The tabs are aligned with the filter
+	detailsCol := termui.NewCol(3.0/4, ui.details)
+	if len(opts.Profiles) > 0 {
+		ui.tabs = newTabs(opts.Profiles)
+		detailsCol = termui.NewCol(3.0/4,
+			termui.NewRow(1.5/10, ui.tabs),
+			termui.NewRow(8.5/10, &detailsPane{ui: &ui}))
+	}
+	historyCol := termui.NewCol(7.0/10,
+		termui.NewCol(8.0/10, ui.routineHist),
+		termui.NewCol(2.0/10, ui.histLegend))
+	if opts.Metrics != "" {
+		ui.metricPlot, ui.metricLegend = newMetricPlot()
+		historyCol = termui.NewCol(7.0/10,
+			termui.NewCol(6.0/10,
+				termui.NewCol(8.0/10, ui.routineHist),
+				termui.NewCol(2.0/10, ui.histLegend)),
+			termui.NewCol(4.0/10,
+				termui.NewCol(7.0/10, ui.metricPlot),
+				termui.NewCol(3.0/10, ui.metricLegend)))
+	}
 	grid.Set(
 		termui.NewRow(3.0/10,
 			termui.NewCol(3.0/10,
 				termui.NewCol(5.0/8, ui.barchart),
 				termui.NewCol(3.0/8, ui.barchartLegend)),
-			termui.NewCol(7.0/10, ui.routineHist),
+			historyCol,
 		),
 		termui.NewRow(7.0/10,
-			termui.NewCol(1.0/6,
+			termui.NewCol(1.0/4,
 				termui.NewRow(1.5/10, ui.filter),
 				termui.NewRow(8.5/10, ui.list)),
-			termui.NewCol(5.0/6, ui.details),
+			detailsCol,
 		),
 	)
 
 	ui.updatePlotTitle()
+	ui.updateLegend()
+	ui.updateConnectionBar()
 
 	return &ui
 }
 
+func (ui *UI) updateLegend() {
+	if !ui.paused {
+		ui.legend.Text = ui.parseErrorBadge() + ui.deadlockBadge() + ui.baselineBadge() + ui.ignoreBadge() + "F1 Help | F2 Pause | F10 Quit"
+		ui.legend.TextStyle.Fg = termui.ColorGreen
+	} else {
+		ui.legend.Text = ui.parseErrorBadge() + ui.deadlockBadge() + ui.baselineBadge() + ui.ignoreBadge() + fmt.Sprintf("PAUSED (%d updates pending) | F1 Help | F2 Resume | F10 Quit", ui.pendingCount)
+		ui.legend.TextStyle.Fg = termui.ColorYellow
+	}
+	ui.layoutFooter()
+}
+
+// layoutFooter places the legend at the bottom right and the status message left of it
+func (ui *UI) layoutFooter() {
+	legendWidth := len(termui.ParseStyles(ui.legend.Text, ui.legend.TextStyle)) + 3
+	ui.legend.SetRect(ui.width-legendWidth, ui.height-5, ui.width-1, ui.height-2)
+	ui.status.SetRect(ui.width/6+2, ui.height-5, ui.width-legendWidth-1, ui.height-2)
+}
+
 func (ui *UI) updatePlotTitle() {
-	ui.routineHist.Title = fmt.Sprintf("History # goroutines (Min: %d Avg: %0.2f Max: %d)",
-		ui.minGoRoutines, ui.avgGoRoutines, ui.maxGoRoutines)
+	summary := ui.stats.Summary(time.Now(), ui.statsWindows[ui.statsWindow])
+	ui.routineHist.Title = fmt.Sprintf("History # goroutines (last %s: Min: %0.f Avg: %0.2f Max: %0.f P50: %0.f P90: %0.f P99: %0.f)",
+		formatWindow(summary.Window), summary.Min, summary.Mean, summary.Max, summary.P50, summary.P90, summary.P99)
+}
+
+// formatWindow returns a short representation of a duration such as 5m or 1h
+func formatWindow(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = s[:len(s)-2]
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = s[:len(s)-2]
+	}
+	return s
+}
+
+// cycleStatsWindow selects the next statistics time window
+func (ui *UI) cycleStatsWindow() {
+	ui.statsWindow = (ui.statsWindow + 1) % len(ui.statsWindows)
+	ui.updatePlotTitle()
+}
+
+// resetStats removes all samples from the statistics
+func (ui *UI) resetStats() {
+	ui.stats.Reset()
+	ui.updatePlotTitle()
+	ui.status.Text = "Statistics reset"
+}
+
+// Style colors of the status categories
+var categoryColors = map[model.Category]string{
+	model.CategoryRunning:  "green",
+	model.CategoryRunnable: "cyan",
+	model.CategoryChannel:  "yellow",
+	model.CategoryLock:     "red",
+	model.CategoryIO:       "blue",
+	model.CategorySleep:    "white",
+	model.CategorySystem:   "magenta",
+	model.CategoryOther:    "clear",
+}
+
+// barColor of a category. Bars of the default text color need a visible background
+func barColor(c model.Category) termui.Color {
+	color := termui.StyleParserColorMap[categoryColors[c]]
+	if color == termui.ColorClear {
+		return termui.Theme.Default.Fg
+	}
+	return color
 }
 
 func (ui *UI) updateStatus() {
-	typeCount := make(map[string]float64)
-	for i := 0; i < len(ui.origData); i++ {
-		num := typeCount[ui.origData[i].Status]
-		typeCount[ui.origData[i].Status] = num + 1
-	}
-
-	types := make([]string, 0, len(typeCount))
-	for key := range typeCount {
-		types = append(types, key)
-	}
-	sort.Strings(types)
-	data := make([]float64, len(types))
-	labels := make([]string, len(types))
-	label := ""
-	uniqueID := 1
-	for idx, t := range types {
-		data[idx] = typeCount[t]
-		newLabel := t[:3]
-		if slices.Contains(labels, newLabel) {
-			newLabel = fmt.Sprintf("%s%d", t[:2], uniqueID)
-			uniqueID++
+	if ui.groupedFormat {
+		ui.updateGroupChart()
+		return
+	}
+	if ui.showWaitHist {
+		ui.updateWaitHist()
+		return
+	}
+
+	statusCount := make(map[model.Status]int)
+	categoryCount := make(map[model.Category]int)
+	for _, r := range ui.origData {
+		statusCount[r.Status]++
+		categoryCount[r.Status.Category()]++
+	}
+
+	states := make([]model.Status, 0, len(statusCount))
+	for key := range statusCount {
+		states = append(states, key)
+	}
+	slices.SortFunc(states, model.CompareStatus)
+
+	data := make([]float64, 0, len(model.Categories))
+	labels := make([]string, 0, len(model.Categories))
+	colors := make([]termui.Color, 0, len(model.Categories))
+	legend := ""
+	for _, c := range model.Categories {
+		if categoryCount[c] == 0 {
+			continue
+		}
+		data = append(data, float64(categoryCount[c]))
+		labels = append(labels, c.Short())
+		colors = append(colors, barColor(c))
+		legend += fmt.Sprintf("[%s: %s](fg:%s)\n", c.Short(), c, categoryColors[c])
+		for _, s := range states {
+			if s.Category() == c {
+				legend += fmt.Sprintf("  %s: %d\n", s, statusCount[s])
+			}
 		}
-		labels[idx] = newLabel
-		label = fmt.Sprintf("%s%s: %s\n", label, newLabel, t)
 	}
+	ui.barchart.Title = "Status"
 	ui.barchart.Data = data
 	ui.barchart.Labels = labels
-	ui.barchartLegend.Text = label
+	ui.barchart.BarColors = colors
+	ui.barchartLegend.Text = legend
 }
 
 func (ui *UI) updateList() {
+	// Single goroutines have no labels
+	_, labelFilter := model.ParseLabelFilter(ui.filter.Text)
+	ui.filter.Title = "Filter"
+	if labelFilter && !ui.groupedFormat {
+		ui.filter.Title = "Filter (no labels in -format=stacks)"
+	}
+	if ui.grouped() {
+		ui.updateGroupList()
+		return
+	}
 	if ui.filter.Text == "" || !ui.filtered {
 		ui.filteredData = ui.origData
+	} else if labelFilter {
+		ui.filteredData = make([]model.Goroutine, 0)
 	} else {
 		ui.filteredData = make([]model.Goroutine, 0)
 		for _, d := range ui.origData {
 			filterText := strings.ToLower(ui.filter.Text)
 			matchID := strings.Contains(strings.ToLower(fmt.Sprintf("%d", d.ID)), filterText)
-			matchStatus := strings.Contains(strings.ToLower(d.Status), filterText)
+			matchStatus := strings.Contains(strings.ToLower(string(d.Status)), filterText)
 			matchCreatedBy := d.CratedBy != nil && strings.Contains(strings.ToLower(d.CratedBy.String()), filterText)
 			matchStackTrace := model.StackContains(d.StackTrace, filterText)
 			matchLockedToThread := d.LockedToThread && strings.Contains("locked to thread", filterText)
@@ -215,17 +457,26 @@ func (ui *UI) updateList() {
 			}
 		}
 	}
+	ui.filteredData = sortRoutines(ui.filteredData, ui.order)
 
 	// Update list
 	ui.list.Rows = make([]string, len(ui.filteredData))
 	for i := 0; i < len(ui.filteredData); i++ {
-		ui.list.Rows[i] = fmt.Sprintf("%05d %s ", ui.filteredData[i].ID, ui.filteredData[i].Status)
+		routine := ui.filteredData[i]
+		row := ui.baselineMark(routine.StackTrace) + fmt.Sprintf("[%05d %s](fg:%s)", routine.ID, routine.Status, categoryColors[routine.Status.Category()])
+		if wait := formatWait(routine.WaitSince); wait != "" {
+			row += " " + wait
+		}
+		if frame, ok := ui.classifier.FirstOwnFrame(routine); ok {
+			row += " " + escape(frame.ShortName())
+		}
+		ui.list.Rows[i] = row
 	}
 
 	if len(ui.filteredData) == 0 {
 		ui.list.SelectedRow = 0
 		ui.details.Text = ""
-		ui.list.Title = "Routines (0/0)"
+		ui.list.Title = fmt.Sprintf("Routines (0/0) by %s", orderNames[ui.order])
 		return
 	}
 
@@ -236,27 +487,106 @@ func (ui *UI) updateList() {
 	}
 
 	selectedData := ui.filteredData[ui.list.SelectedRow]
-	trace := ""
-	for _, t := range selectedData.StackTrace {
-		trace += fmt.Sprintf("  %s\n", t.String())
-	}
+	trace := ui.formatTrace(selectedData.StackTrace)
 	createdBy := ""
 	if selectedData.CratedBy != nil {
-		createdBy = fmt.Sprintf("Created by:\n  %s\n\n", selectedData.CratedBy.String())
+		createdBy = fmt.Sprintf("Created by:\n  %s\n\n", ui.frameText(*selectedData.CratedBy))
 	}
 	lockedToThread := ""
 	if selectedData.LockedToThread {
 		lockedToThread = " [locked to thread](mod:bold)"
 	}
-	ui.details.Text = fmt.Sprintf("ID: [%d](mod:bold)\n\nStatus: [%s](mod:bold)\n\nWait Since: [%d min](mod:bold)%s\n\n%sTrace:\n%s",
+	for _, a := range selectedData.Annotations {
+		lockedToThread += fmt.Sprintf(" [%s](mod:bold)", escape(a))
+	}
+	waitSince := ""
+	if selectedData.WaitSince > 0 {
+		waitSince = fmt.Sprintf("\n\nWait Since: [%s](mod:bold)", formatWindow(selectedData.WaitSince))
+	}
+	ui.details.Text = fmt.Sprintf("ID: [%d](mod:bold)\n\nStatus: [%s](mod:bold)%s%s\n\n%s%sTrace:\n%s",
 		selectedData.ID,
 		selectedData.Status,
-		selectedData.WaitSinceMin,
 		lockedToThread,
+		waitSince,
+		ui.baselineDetails(selectedData.StackTrace),
 		createdBy,
 		trace)
 
-	ui.list.Title = fmt.Sprintf("Routines (%d/%d)", ui.list.SelectedRow+1, len(ui.list.Rows))
+	ui.list.Title = fmt.Sprintf("Routines (%d/%d) by %s", ui.list.SelectedRow+1, len(ui.list.Rows), orderNames[ui.order])
+}
+
+// selected returns the currently selected goroutine
+func (ui *UI) selected() (model.Goroutine, bool) {
+	if ui.list.SelectedRow < 0 || ui.list.SelectedRow >= len(ui.filteredData) {
+		return model.Goroutine{}, false
+	}
+	return ui.filteredData[ui.list.SelectedRow], true
+}
+
+// copySelected copies the selected goroutine in the given format ("stack", "json" or "location") to the clipboard
+func (ui *UI) copySelected(format string) {
+	if ui.grouped() {
+		ui.copySelectedGroup(format)
+		return
+	}
+	routine, ok := ui.selected()
+	if !ok {
+		ui.status.Text = "Nothing selected"
+		return
+	}
+
+	var text string
+	switch format {
+	case "json":
+		data, err := json.MarshalIndent(routine, "", "  ")
+		if err != nil {
+			ui.status.Text = fmt.Sprintf("Failed to encode goroutine: %s", err.Error())
+			return
+		}
+		text = string(data)
+	case "location":
+		if len(routine.StackTrace) == 0 {
+			ui.status.Text = fmt.Sprintf("Goroutine %d has no stack frames", routine.ID)
+			return
+		}
+		text = routine.StackTrace[0].Location()
+	default:
+		text = routine.Text()
+	}
+
+	msg, err := ui.clipboard.copy(text)
+	if err != nil {
+		log.Print(err.Error())
+		ui.status.Text = err.Error()
+		return
+	}
+	ui.status.Text = fmt.Sprintf("Goroutine %d %s %s", routine.ID, format, msg)
+}
+
+// saveSnapshot writes the displayed routines to a file. Only the filtered routines are written if filtered is set
+func (ui *UI) saveSnapshot(filtered bool) {
+	if ui.grouped() {
+		ui.saveGroupSnapshot(filtered)
+		return
+	}
+	routines := ui.origData
+	if filtered {
+		routines = ui.filteredData
+	}
+	path, err := saveSnapshot(ui.opts.SnapshotDir, ui.opts.SnapshotFormat, routines)
+	if err != nil {
+		log.Print(err.Error())
+		ui.status.Text = err.Error()
+		return
+	}
+	ui.status.Text = fmt.Sprintf("Saved %d goroutines to %s", len(routines), path)
+}
+
+func (ui *UI) render(items ...termui.Drawable) {
+	if view := ui.overlayView(); view != nil {
+		items = append(items, view)
+	}
+	termui.Render(append([]termui.Drawable{ui.connBar, ui.grid, ui.legend, ui.status}, items...)...)
 }
 
 // Stop UI and close all event listeners
@@ -266,20 +596,103 @@ func (ui *UI) Stop() {
 
 func (ui *UI) resize(width, height int) {
 	log.Printf("Resize to: (%d,%d)", width, height)
-	ui.paused.SetRect(width/2.0-25, height/4.0-4, width/2.0+25, height/4.0+4)
-	ui.help.SetRect(width/2.0-20, height/4.0-10, width/2.0+20, height/4.0+10)
-	ui.legend.SetRect(width-35, height-4, width-1, height-1)
-	ui.grid.SetRect(0, 0, width, height)
+	ui.width = width
+	ui.height = height
+	helpHeight := strings.Count(ui.help.Text, "\n") + 7
+	ui.help.SetRect(width/2.0-20, height/2.0-helpHeight/2, width/2.0+20, height/2.0+helpHeight-helpHeight/2)
+	ui.parseErrorView.SetRect(5, 3, width-5, height-4)
+	ui.deadlockView.SetRect(5, 3, width-5, height-4)
+	ui.lockView.SetRect(5, 3, width-5, height-4)
+	ui.channelView.SetRect(5, 3, width-5, height-4)
+	ui.labelBrowser.SetRect(width/4, 3, width-width/4, height-4)
+	ui.layoutFooter()
+	// Last line is reserved for the connection status bar. Blocks without border still keep space for it
+	ui.grid.SetRect(0, 0, width, height-1)
+	ui.connBar.SetRect(-1, height-2, width+1, height+1)
+	ui.updateHistPlot()
+	ui.updateOverlay()
+}
+
+// update UI with a new snapshot of routines
+func (ui *UI) update(scrape client.Scrape) {
+	ui.scrape = scrape
+	ui.setData(scrape)
+	ui.setProfiles(scrape.Profiles)
+	ui.updateProfileView()
+	ui.updateHistPlot()
+	ui.updatePlotTitle()
+}
+
+// record adds the goroutine counts and runtime metrics of the scrape to the history and statistics at the time of the scrape.
+// Also called while paused, so the history has no gaps
+func (ui *UI) record(scrape client.Scrape) {
+	routines, groups := scrape.Routines, scrape.Groups
+	if !ui.showIgnored {
+		routines, _ = ui.ignore.FilterRoutines(routines)
+		groups, _ = ui.ignore.FilterGroups(groups)
+	}
+	total := len(routines)
+	if groups != nil {
+		total = model.Total(groups)
+	}
+	ui.addHistory(scrape.Time, routines, total)
+	if scrape.Metrics != nil {
+		ui.addMetrics(scrape.Time, *scrape.Metrics)
+	}
+	ui.stats.Add(scrape.Time, float64(total))
+}
+
+// setData shows the goroutines of the scrape which are not ignored
+func (ui *UI) setData(scrape client.Scrape) {
+	routines, groups := scrape.Routines, scrape.Groups
+	ui.ignoredCount = 0
+	if !ui.showIgnored {
+		var ignoredGroups int
+		routines, ui.ignoredCount = ui.ignore.FilterRoutines(routines)
+		groups, ignoredGroups = ui.ignore.FilterGroups(groups)
+		ui.ignoredCount += ignoredGroups
+	}
+	ui.origData = routines
+	ui.groupedFormat = groups != nil
+	if ui.groupedFormat {
+		ui.groups = groups
+		routines = model.Stacks(groups)
+	} else if ui.groupView || ui.baseline != nil {
+		ui.groups = model.GroupByStack(routines)
+	}
+	ui.classifier = ui.modules.Detect(routines)
+	ui.checkBaseline(ui.groups)
+	ui.checkDeadlocks()
+	ui.moduleRoots = model.ModuleRoots(routines, ui.classifier)
+	ui.updateLegend()
+	ui.updateList()
+	ui.updateStatus()
+}
+
+// togglePause freezes the displayed data. Snapshots received while paused are recorded in the history and statistics
+// and the latest one is applied on resume
+func (ui *UI) togglePause() {
+	ui.paused = !ui.paused
+	if !ui.paused && ui.pendingCount > 0 {
+		ui.update(ui.pending)
+	}
+	ui.pending = client.Scrape{}
+	ui.pendingCount = 0
+	ui.updateLegend()
 }
 
 // Run UI in fullscreen mode
-func (ui *UI) Run(terminate chan<- error, routinesUpdate <-chan []model.Goroutine) {
+func (ui *UI) Run(terminate chan<- error, routinesUpdate <-chan client.Scrape) {
 	ui.updateList()
 
 	termWidth, termHeight := termui.TerminalDimensions()
 	ui.resize(termWidth, termHeight)
 
-	termui.Render(ui.grid, ui.legend)
+	ui.render()
+
+	// Refresh the time since the last scrape
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
 
 	pollEvents := termui.PollEvents()
 	for {
@@ -296,60 +709,195 @@ func (ui *UI) Run(terminate chan<- error, routinesUpdate <-chan []model.Goroutin
 					ui.resize(resized.Width, resized.Height)
 				}
 			case termui.KeyboardEvent:
-				terminateEvent := ui.handleKeyEvent(evt.ID, pollEvents)
+				var terminateEvent bool
+				if ui.overlay != overlayNone {
+					terminateEvent = ui.handleOverlayKey(evt.ID)
+				} else {
+					terminateEvent = ui.handleKeyEvent(evt.ID, pollEvents)
+				}
 				if terminateEvent {
 					terminate <- nil
 					return
 				}
 			}
-		case routines := <-routinesUpdate:
-			// History data size cannot be limited in termui. This is a workaround
-			var keepRoutineHist = (ui.routineHist.Dx() - 10) >> 1
-			ui.origData = routines
-			if len(ui.routineHist.Data[0]) >= keepRoutineHist {
-				ui.routineHist.Data[0] = ui.routineHist.Data[0][1:]
-			}
-			ui.routineHist.Data[0] = append(ui.routineHist.Data[0], float64(len(routines)))
-
-			if ui.minGoRoutines == 0 || len(routines) < ui.minGoRoutines {
-				ui.minGoRoutines = len(routines)
-			}
-			if len(routines) > ui.maxGoRoutines {
-				ui.maxGoRoutines = len(routines)
+		case scrape := <-routinesUpdate:
+			ui.updateConnection(scrape)
+			if scrape.Err != nil {
+				break
 			}
-			if ui.avgGoRoutines > 0 {
-				ui.avgGoRoutines = (ui.avgGoRoutines + float64(len(routines))) / 2.0
+			ui.record(scrape)
+			if ui.paused {
+				// Runtime profiles are only part of some scrapes
+				if len(scrape.Profiles) == 0 {
+					scrape.Profiles = ui.pending.Profiles
+				}
+				ui.pending = scrape
+				ui.pendingCount++
+				ui.updateLegend()
 			} else {
-				ui.avgGoRoutines = float64(len(routines))
+				ui.update(scrape)
 			}
-			ui.updatePlotTitle()
-			ui.updateList()
-			ui.updateStatus()
+			ui.updateOverlay()
+		case <-ticker.C:
+			ui.updateConnectionBar()
 		}
 
-		termui.Render(ui.grid, ui.legend)
+		ui.render()
+	}
+}
+
+// openOverlay shows the view on top of the main screen until it is closed
+func (ui *UI) openOverlay(view overlay) {
+	ui.overlay = view
+	ui.updateOverlay()
+}
+
+// overlayView returns the widget of the open overlay. Nil if none is open
+func (ui *UI) overlayView() termui.Drawable {
+	switch ui.overlay {
+	case overlayParseErrors:
+		return ui.parseErrorView
+	case overlayLabels:
+		return ui.labelBrowser
+	case overlayDeadlocks:
+		return ui.deadlockView
+	case overlayLocks:
+		return ui.lockView
+	case overlayChannels:
+		return ui.channelView
+	}
+	return nil
+}
+
+// updateOverlay refreshes the content of the open overlay
+func (ui *UI) updateOverlay() {
+	switch ui.overlay {
+	case overlayParseErrors:
+		ui.updateParseErrorView()
+	case overlayLabels:
+		ui.updateLabelBrowser()
+	case overlayDeadlocks:
+		ui.updateDeadlockView()
+	case overlayLocks:
+		ui.updateLockView()
+	case overlayChannels:
+		ui.updateChannelView()
 	}
 }
 
+// handleOverlayKey handles the keys while an overlay is open
+func (ui *UI) handleOverlayKey(keyID string) (terminate bool) {
+	if keyID == "<C-c>" || keyID == "<F10>" {
+		return true
+	}
+	switch ui.overlay {
+	case overlayParseErrors, overlayDeadlocks:
+		// Any key closes the view
+		ui.overlay = overlayNone
+	case overlayLabels:
+		ui.handleLabelKey(keyID)
+	case overlayLocks:
+		ui.handleListKey(ui.lockView, keyID, "<C-x>")
+	case overlayChannels:
+		ui.handleListKey(ui.channelView, keyID, "<C-n>")
+	}
+	return false
+}
+
+// handleListKey closes a list overlay with Escape or the key which opened it and scrolls it with the other keys
+func (ui *UI) handleListKey(list *widgets.List, keyID, openKey string) {
+	if keyID == "<Escape>" || keyID == openKey {
+		ui.overlay = overlayNone
+		return
+	}
+	scrollList(list, keyID)
+}
+
+// scrollList scrolls the list of an overlay. Returns false if the key does not scroll
+func scrollList(list *widgets.List, keyID string) bool {
+	switch keyID {
+	case "<Down>":
+		list.ScrollDown()
+	case "<Up>":
+		list.ScrollUp()
+	case "<PageDown>":
+		list.ScrollPageDown()
+	case "<PageUp>":
+		list.ScrollPageUp()
+	default:
+		return false
+	}
+	return true
+}
+
 func (ui *UI) handleKeyEvent(keyID string, pollEvents <-chan termui.Event) (terminate bool) {
+	if ui.scrollProfile(keyID) {
+		return false
+	}
 	switch keyID {
 	case "<C-c>", "<F10>":
 		return true
 	case "<F1>":
-		termui.Render(ui.grid, ui.legend, ui.help)
+		ui.render(ui.help)
 		e := <-pollEvents
 		if e.ID == "<C-c>" || e.ID == "<F10>" {
 			return true
 		}
-		termui.Render(ui.grid, ui.legend)
+		ui.render()
 	case "<F2>":
-		// Pause
-		termui.Render(ui.grid, ui.legend, ui.paused)
-		e := <-pollEvents
-		if e.ID == "<C-c>" || e.ID == "<F10>" {
-			return true
-		}
-		termui.Render(ui.grid, ui.legend)
+		ui.togglePause()
+	case "<F9>":
+		ui.openOverlay(overlayParseErrors)
+	case "<F3>":
+		ui.cycleStatsWindow()
+	case "<F4>":
+		ui.resetStats()
+	case "<F5>":
+		ui.selectNextHistSeries()
+	case "<F6>":
+		ui.toggleHistSeries()
+	case "<F7>":
+		ui.zoomHist(1)
+	case "<F8>":
+		ui.zoomHist(-1)
+	case "<C-o>":
+		ui.cycleOrder()
+	case "<C-b>":
+		ui.toggleBarchart()
+	case "<C-t>":
+		ui.toggleStdlib()
+	case "<C-p>":
+		ui.cyclePathMode()
+	case "<C-g>":
+		ui.cycleGrouping()
+	case "<C-k>":
+		ui.openOverlay(overlayLabels)
+	case "<C-d>":
+		ui.openOverlay(overlayDeadlocks)
+	case "<C-x>":
+		ui.openOverlay(overlayLocks)
+	case "<C-n>":
+		ui.openOverlay(overlayChannels)
+	case "<Tab>":
+		ui.nextTab()
+	case "<Right>":
+		ui.focusProfile(true)
+	case "<Left>":
+		ui.focusProfile(false)
+	case "<C-u>":
+		ui.toggleIgnored()
+	case "<C-e>":
+		ui.saveBaseline()
+	case "<C-y>":
+		ui.copySelected("stack")
+	case "<C-j>":
+		ui.copySelected("json")
+	case "<C-l>":
+		ui.copySelected("location")
+	case "<C-s>":
+		ui.saveSnapshot(false)
+	case "<C-w>":
+		ui.saveSnapshot(true)
 	case "<Down>":
 		ui.list.ScrollDown()
 		ui.updateList()
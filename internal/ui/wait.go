@@ -0,0 +1,121 @@
+package ui
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/becheran/roumon/internal/model"
+
+	termui "github.com/gizak/termui/v3"
+)
+
+// Orders of the routine list
+const (
+	orderDump   = iota // Order of the goroutine dump
+	orderID            // Ascending ID
+	orderWait          // Longest waiting first
+	orderStatus        // By status category
+	orderCount
+)
+
+var orderNames = []string{"dump order", "ID", "wait time", "status"}
+
+// waitBucket of the wait time histogram. Contains all wait times up to but excluding max
+type waitBucket struct {
+	label string
+	max   time.Duration
+}
+
+var waitBuckets = []waitBucket{
+	{"<1m", time.Minute},
+	{"1m", 5 * time.Minute},
+	{"5m", 15 * time.Minute},
+	{"15m", time.Hour},
+	{"1h", 6 * time.Hour},
+	{"6h", math.MaxInt64},
+}
+
+// formatWait returns the wait time of a routine or an empty string if not waiting for at least one minute
+func formatWait(d time.Duration) string {
+	if d < time.Minute {
+		return ""
+	}
+	return formatWindow(d.Truncate(time.Minute))
+}
+
+// sortRoutines returns the routines in the given order. The input slice is not modified
+func sortRoutines(routines []model.Goroutine, order int) []model.Goroutine {
+	if order == orderDump {
+		return routines
+	}
+	sorted := slices.Clone(routines)
+	slices.SortStableFunc(sorted, func(a, b model.Goroutine) int {
+		switch order {
+		case orderWait:
+			if c := cmp.Compare(b.WaitSince, a.WaitSince); c != 0 {
+				return c
+			}
+		case orderStatus:
+			if c := model.CompareStatus(a.Status, b.Status); c != 0 {
+				return c
+			}
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return sorted
+}
+
+// cycleOrder switches to the next order of the routine list
+func (ui *UI) cycleOrder() {
+	if ui.grouped() {
+		ui.status.Text = "Groups are sorted by count"
+		return
+	}
+	ui.order = (ui.order + 1) % orderCount
+	ui.updateList()
+	ui.status.Text = fmt.Sprintf("Sorted by %s", orderNames[ui.order])
+}
+
+// toggleBarchart switches the bar chart between status overview and wait time histogram
+func (ui *UI) toggleBarchart() {
+	ui.showWaitHist = !ui.showWaitHist
+	ui.updateStatus()
+}
+
+// updateWaitHist shows the histogram of wait times of all waiting routines in the bar chart
+func (ui *UI) updateWaitHist() {
+	data := make([]float64, len(waitBuckets))
+	for _, r := range ui.origData {
+		if !r.Status.Waiting() {
+			continue
+		}
+		for i, b := range waitBuckets {
+			if r.WaitSince < b.max {
+				data[i]++
+				break
+			}
+		}
+	}
+
+	labels := make([]string, len(waitBuckets))
+	legend := ""
+	for i, b := range waitBuckets {
+		labels[i] = b.label
+		switch {
+		case i == 0:
+			legend += fmt.Sprintf("%s: less than %s\n", b.label, formatWindow(b.max))
+		case b.max == math.MaxInt64:
+			legend += fmt.Sprintf("%s: %s and more\n", b.label, formatWindow(waitBuckets[i-1].max))
+		default:
+			legend += fmt.Sprintf("%s: %s - %s\n", b.label, formatWindow(waitBuckets[i-1].max), formatWindow(b.max))
+		}
+	}
+	ui.barchart.Title = "Wait time"
+	ui.barchart.Data = data
+	ui.barchart.Labels = labels
+	ui.barchart.BarColors = []termui.Color{termui.ColorYellow}
+	ui.barchartLegend.Text = legend
+}
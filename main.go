@@ -6,22 +6,55 @@ import (
 	"io"
 	"log"
 	"os"
+	"path/filepath"
 	"runtime/debug"
+	"time"
 
+	"github.com/becheran/roumon/internal/baseline"
 	"github.com/becheran/roumon/internal/client"
-	"github.com/becheran/roumon/internal/model"
 	"github.com/becheran/roumon/internal/ui"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "diff":
+			os.Exit(runDiff(os.Args[2:]))
+		case "check":
+			os.Exit(runCheck(os.Args[2:]))
+		}
+	}
+
 	var host string
 	var dbgFile string
 	var port int
 	var versionFlag bool
+	var strict bool
+	var format string
+	var baselineFile string
+	var profiles string
+	var profileInterval time.Duration
+	var uiOpts ui.Options
 	flag.StringVar(&host, "host", "localhost", "The pprof server IP or hostname")
 	flag.IntVar(&port, "port", 6060, "The pprof server port")
 	flag.StringVar(&dbgFile, "debug", "", "Path to debug file")
 	flag.BoolVar(&versionFlag, "v", false, "Print version of roumon and exit")
+	flag.BoolVar(&strict, "strict", false, "Fail the whole scrape if a part of the goroutine dump cannot be parsed")
+	flag.StringVar(&format, "format", client.FormatStacks, "Goroutine profile format. One of \"stacks\" (debug=2), \"grouped\" (debug=1, cheaper for many goroutines) or \"proto\" (debug=0, with labels)")
+	flag.StringVar(&uiOpts.ClipboardMode, "clipboard", ui.ClipboardAuto, "Clipboard mode. One of \"auto\", \"osc52\" or \"file\"")
+	flag.StringVar(&uiOpts.SnapshotDir, "snapshot-dir", ".", "Directory to save snapshots to")
+	flag.StringVar(&uiOpts.SnapshotFormat, "snapshot-format", ui.SnapshotText, "Format of saved snapshots. One of \"text\" or \"json\"")
+	flag.DurationVar(&uiOpts.StatsWindow, "window", time.Minute, "Initial time window of the goroutine statistics")
+	flag.DurationVar(&uiOpts.ScrapeInterval, "interval", client.DefaultInterval, "Interval of scraping the goroutines")
+	flag.DurationVar(&uiOpts.HistoryLength, "history", 6*time.Hour, "Time span of goroutine history to keep")
+	flag.StringVar(&uiOpts.Modules, "module", "", "Comma separated import path prefixes of own code. Detected automatically if empty")
+	ignoreRules := addIgnoreFlags(flag.CommandLine)
+	flag.DurationVar(&uiOpts.DeadlockAfter, "deadlock-after", time.Minute, "Minimum wait time of blocked goroutines for deadlock detection and stuck channel senders")
+	flag.StringVar(&profiles, "profiles", "", "Comma separated runtime profiles to show as tabs next to the goroutine details. Any of \"block\", \"mutex\" or \"threadcreate\"")
+	flag.DurationVar(&profileInterval, "profile-interval", client.DefaultProfileInterval, "Interval of fetching the runtime profiles and metrics")
+	flag.StringVar(&uiOpts.Metrics, "metrics", "", "Source of heap, GC and thread metrics plotted next to the goroutine history. One of \"heap\" (/debug/pprof/heap?debug=1) or \"expvar\" (/debug/vars). Not fetched if empty")
+	flag.StringVar(&baselineFile, "baseline", "", "Path to a baseline file. Stacks not covered by the baseline are highlighted")
+	flag.StringVar(&uiOpts.ClipboardFile, "clipboard-file", filepath.Join(os.TempDir(), "roumon-clipboard.txt"), "File to write copied text to if OSC 52 is not used")
 	flag.Parse()
 
 	version := "dev"
@@ -34,6 +67,51 @@ func main() {
 		return
 	}
 
+	if format != client.FormatStacks && format != client.FormatGrouped && format != client.FormatProto {
+		fmt.Printf("Invalid format %q. Must be one of %q, %q or %q\n", format, client.FormatStacks, client.FormatGrouped, client.FormatProto)
+		os.Exit(2)
+	}
+
+	if uiOpts.ScrapeInterval <= 0 {
+		fmt.Printf("Invalid interval %s. Must be positive\n", uiOpts.ScrapeInterval)
+		os.Exit(2)
+	}
+
+	if uiOpts.ClipboardMode != ui.ClipboardAuto && uiOpts.ClipboardMode != ui.ClipboardOSC52 && uiOpts.ClipboardMode != ui.ClipboardFile {
+		fmt.Printf("Invalid clipboard mode %q. Must be one of %q, %q or %q\n", uiOpts.ClipboardMode, ui.ClipboardAuto, ui.ClipboardOSC52, ui.ClipboardFile)
+		os.Exit(2)
+	}
+
+	if uiOpts.SnapshotFormat != ui.SnapshotText && uiOpts.SnapshotFormat != ui.SnapshotJSON {
+		fmt.Printf("Invalid snapshot format %q. Must be one of %q or %q\n", uiOpts.SnapshotFormat, ui.SnapshotText, ui.SnapshotJSON)
+		os.Exit(2)
+	}
+
+	if uiOpts.Metrics != "" && uiOpts.Metrics != client.MetricsHeap && uiOpts.Metrics != client.MetricsExpvar {
+		fmt.Printf("Invalid metrics source %q. Must be one of %q or %q\n", uiOpts.Metrics, client.MetricsHeap, client.MetricsExpvar)
+		os.Exit(2)
+	}
+
+	var err error
+	if uiOpts.Profiles, err = client.ParseProfiles(profiles); err != nil {
+		fmt.Println(err.Error())
+		os.Exit(2)
+	}
+
+	if uiOpts.Ignore, err = ignoreRules(); err != nil {
+		fmt.Println(err.Error())
+		os.Exit(2)
+	}
+
+	if baselineFile != "" {
+		b, err := baseline.Load(baselineFile)
+		if err != nil {
+			fmt.Println(err.Error())
+			os.Exit(2)
+		}
+		uiOpts.Baseline = b
+	}
+
 	if len(dbgFile) > 0 {
 		f, err := os.OpenFile(dbgFile, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
 		if err != nil {
@@ -52,15 +130,21 @@ func main() {
 	log.Printf("Start roumon (%s)", version)
 
 	c := client.NewClient(host, port)
-	ui := ui.NewUI()
+	c.Strict = strict
+	c.Format = format
+	c.Interval = uiOpts.ScrapeInterval
+	c.Profiles = uiOpts.Profiles
+	c.ProfileInterval = profileInterval
+	c.Metrics = uiOpts.Metrics
+	ui := ui.NewUI(uiOpts)
 
 	terminate := make(chan error)
 
-	routinesUpdate := make(chan []model.Goroutine)
-	go c.Run(terminate, routinesUpdate)
+	routinesUpdate := make(chan client.Scrape)
+	go c.Run(routinesUpdate)
 	go ui.Run(terminate, routinesUpdate)
 
-	err := <-terminate
+	err = <-terminate
 	ui.Stop()
 
 	if err != nil {
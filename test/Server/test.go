@@ -1,11 +1,13 @@
 package main
 
 import (
+	"context"
 	"log"
 	"math/rand"
 	"net/http"
 	_ "net/http/pprof"
 	"os"
+	"runtime/pprof"
 	"time"
 )
 
@@ -17,7 +19,7 @@ func main() {
 	go func() {
 		for {
 			time.Sleep(1 * time.Second)
-			go func() {
+			go pprof.Do(context.Background(), pprof.Labels("worker", "sleep"), func(context.Context) {
 				min := 10
 				max := 5000
 				randSleepMSec := rand.Intn(max-min) + min
@@ -25,7 +27,7 @@ func main() {
 				println("update")
 				randSleepMSec = rand.Intn(max-min) + min
 				time.Sleep(time.Duration(randSleepMSec) * time.Millisecond)
-			}()
+			})
 		}
 	}()
 	c := make(chan os.Signal, 1)